@@ -0,0 +1,21 @@
+package main
+
+// completer adapts the completions of a UCL instance to readline.
+type completer struct {
+	repl *repl
+}
+
+func (c completer) Do(line []rune, pos int) ([][]rune, int) {
+	src := string(line[:pos])
+	cs := c.repl.inst.Complete(src, len(src))
+	if len(cs) == 0 {
+		return nil, 0
+	}
+
+	typed := []rune(src[cs[0].Start:])
+	candidates := make([][]rune, 0, len(cs))
+	for _, comp := range cs {
+		candidates = append(candidates, []rune(comp.Text)[len(typed):])
+	}
+	return candidates, len(typed)
+}
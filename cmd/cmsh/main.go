@@ -2,33 +2,122 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
 	"github.com/chzyer/readline"
-	"log"
 	"ucl.lmika.dev/ucl"
 	"ucl.lmika.dev/ucl/builtins"
 )
 
+var displayers = map[string]ucl.Displayer{
+	"repr":  ucl.ReprDisplayer{},
+	"plain": ucl.PlainDisplayer{},
+	"table": ucl.TableDisplayer{},
+	"tree":  ucl.TreeDisplayer{},
+	"json":  ucl.JSONDisplayer{Indent: "  "},
+}
+
+// displayer is used to display the results of commands entered in the REPL
+var displayer ucl.Displayer = ucl.ReprDisplayer{}
+
 func main() {
-	rl, err := readline.New("> ")
-	if err != nil {
-		panic(err)
+	expr := flag.String("c", "", "evaluate the expression and exit")
+	output := flag.String("output", "repr", "display results in the REPL as repr, plain, table, tree or json")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: cmsh [-output MODE] [-c EXPR | SCRIPT | -] [ARGS...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	d, ok := displayers[*output]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "cmsh: unknown output mode: %v\n", *output)
+		os.Exit(2)
+	}
+	displayer = d
+
+	ctx := context.Background()
+	args := flag.Args()
+
+	switch {
+	case *expr != "":
+		os.Exit(runScript(ctx, *expr, args))
+	case len(args) > 0:
+		src, err := readScript(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cmsh: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(runScript(ctx, src, args[1:]))
+	case !readline.IsTerminal(int(os.Stdin.Fd())):
+		src, err := readScript("-")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cmsh: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(runScript(ctx, src, nil))
 	}
-	defer rl.Close()
 
-	inst := ucl.New(
+	os.Exit(newREPL().run(ctx))
+}
+
+func newInst(args []string) *ucl.Inst {
+	// Passed as a []any so that $args is a list, rather than a proxy of the Go slice
+	argList := make([]any, len(args))
+	for i, a := range args {
+		argList[i] = a
+	}
+
+	return ucl.New(
+		ucl.WithDisplayer(displayer),
+		ucl.WithOrderedHashes(),
+		ucl.WithMissingBuiltinHandler(builtins.ExecMissingBuiltinHandler()),
 		ucl.WithModule(builtins.OS()),
 		ucl.WithModule(builtins.FS(nil)),
+		ucl.WithModule(builtins.JSON()),
+		ucl.WithModule(builtins.Strs()),
+		ucl.WithModule(builtins.RE()),
+		ucl.WithModule(builtins.Time()),
+		ucl.WithModule(builtins.Math()),
+		ucl.WithUnprefixedModule(ucl.Module{
+			Name: "cmsh",
+			Vars: map[string]any{"args": argList},
+		}),
 	)
-	ctx := context.Background()
+}
 
-	for {
-		line, err := rl.Readline()
-		if err != nil { // io.EOF
-			break
-		}
+// readScript reads the script at path, or from stdin if path is "-".
+func readScript(path string) (string, error) {
+	if path == "-" {
+		src, err := io.ReadAll(os.Stdin)
+		return string(src), err
+	}
 
-		if err := ucl.EvalAndDisplay(ctx, inst, line); err != nil {
-			log.Printf("%T: %v", err, err)
-		}
+	src, err := os.ReadFile(path)
+	return string(src), err
+}
+
+// runScript evaluates a script and returns the exit status of the process.
+func runScript(ctx context.Context, src string, args []string) int {
+	inst := newInst(args)
+	if _, err := inst.Eval(ctx, src); err != nil {
+		return exitCode(err)
+	}
+	return 0
+}
+
+// exitCode returns the exit status for an error returned from evaluating a script, reporting
+// it to stderr unless it was raised by exit.
+func exitCode(err error) int {
+	var exitErr ucl.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
 	}
+
+	fmt.Fprintf(os.Stderr, "cmsh: %v\n", err)
+	return 1
 }
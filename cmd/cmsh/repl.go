@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/chzyer/readline"
+	"ucl.lmika.dev/ucl"
+)
+
+const (
+	prompt         = "> "
+	continuePrompt = ". "
+)
+
+type repl struct {
+	inst *ucl.Inst
+}
+
+func newREPL() *repl {
+	return &repl{inst: newInst(nil)}
+}
+
+// run reads and evaluates commands until EOF or exit, returning the exit status. Input
+// containing unclosed blocks, lists or strings is continued on the following lines.
+func (r *repl) run(ctx context.Context) int {
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:       prompt,
+		HistoryFile:  historyFile(),
+		AutoComplete: completer{repl: r},
+
+		// History is saved once the whole command has been entered, so multi-line commands
+		// can be recalled as one.
+		DisableAutoSaveHistory: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer rl.Close()
+
+	var lines []string
+	for {
+		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			lines = nil
+			rl.SetPrompt(prompt)
+			continue
+		} else if err != nil { // io.EOF
+			break
+		}
+
+		lines = append(lines, line)
+		src := strings.Join(lines, "\n")
+		if ucl.IsIncomplete(src) {
+			rl.SetPrompt(continuePrompt)
+			continue
+		}
+		lines = nil
+		rl.SetPrompt(prompt)
+
+		if strings.TrimSpace(src) == "" {
+			continue
+		}
+
+		_ = rl.SaveHistory(src)
+
+		if strings.HasPrefix(src, ":") {
+			if err := r.metaCommand(rl.Stdout(), strings.TrimSpace(src)); err != nil {
+				log.Printf("%v", err)
+			}
+			continue
+		}
+
+		if err := ucl.EvalAndDisplay(ctx, r.inst, src); err != nil {
+			var exitErr ucl.ExitError
+			if errors.As(err, &exitErr) {
+				return exitErr.Code
+			}
+			log.Printf("%T: %v", err, err)
+		}
+	}
+	return 0
+}
+
+// metaCommand runs one of the REPL commands, which start with a colon:
+//
+//	:reset  discards all variables and procs by starting a new instance
+//	:vars   lists the variables and their values
+//	:procs  lists the procs with their descriptions
+func (r *repl) metaCommand(w io.Writer, cmd string) error {
+	switch cmd {
+	case ":reset":
+		r.inst = newInst(nil)
+		return nil
+	case ":vars":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, name := range r.inst.VarNames() {
+			val, _ := r.inst.LookupVar(name)
+			fmt.Fprintf(tw, "$%v\t%v\n", name, val)
+		}
+		return tw.Flush()
+	case ":procs":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, name := range r.inst.ProcNames() {
+			doc, _ := r.inst.Describe(name)
+			summary, _, _ := strings.Cut(doc.Description, "\n")
+			fmt.Fprintf(tw, "%v\t%v\n", name, summary)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown command: %v (expected :reset, :vars or :procs)", cmd)
+}
+
+// historyFile returns the path of the file used to persist the history between sessions,
+// or the empty string if the home directory cannot be determined.
+func historyFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cmsh_history")
+}
@@ -1,6 +1,8 @@
 package ucl
 
 import (
+	"bytes"
+	"errors"
 	"io"
 	"strings"
 
@@ -9,8 +11,9 @@ import (
 )
 
 type astLiteral struct {
-	Str *string `parser:"@String"`
-	Int *int    `parser:"| @Int"`
+	Str   *string  `parser:"@String"`
+	Float *float64 `parser:"| @Float"`
+	Int   *int     `parser:"| @Int"`
 }
 
 type astIdentNames struct {
@@ -40,10 +43,20 @@ type astListOrHash struct {
 }
 
 type astBlock struct {
+	Tokens     []lexer.Token
 	Names      []string         `parser:"LC NL* (PIPE @Ident+ PIPE NL*)?"`
 	Statements []*astStatements `parser:"@@? NL* RC"`
 }
 
+// source returns the source text of the block, including the braces.
+func (b *astBlock) source() string {
+	sb := strings.Builder{}
+	for _, tok := range b.Tokens {
+		sb.WriteString(tok.Value)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
 type astMaybeSub struct {
 	Sub *astPipeline `parser:"@@?"`
 }
@@ -51,7 +64,7 @@ type astMaybeSub struct {
 type astCmdArg struct {
 	Literal    *astLiteral    `parser:"@@"`
 	Ident      *astIdentNames `parser:"| @@"`
-	Var        *string        `parser:"| DOLLAR @Ident"`
+	Var        *astIdentNames `parser:"| DOLLAR @@"`
 	MaybeSub   *astMaybeSub   `parser:"| LP @@ RP"`
 	ListOrHash *astListOrHash `parser:"| @@"`
 	Block      *astBlock      `parser:"| @@"`
@@ -68,6 +81,7 @@ type astDot struct {
 }
 
 type astCmd struct {
+	Pos  lexer.Position
 	Name astDot   `parser:"@@"`
 	Args []astDot `parser:"@@*"`
 }
@@ -84,6 +98,27 @@ type astStatements struct {
 
 type astScript struct {
 	Statements *astStatements `parser:"NL* (@@ NL*)?"`
+
+	// docComments are the text of the comments appearing on a line by themselves, keyed by
+	// line number
+	docComments map[int]string
+}
+
+// docComment returns the block of comment lines appearing directly above line.
+func (s *astScript) docComment(line int) string {
+	var lines []string
+	for l := line - 1; l > 0; l-- {
+		c, ok := s.docComments[l]
+		if !ok {
+			break
+		}
+		lines = append(lines, c)
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return strings.Join(lines, "\n")
 }
 
 var scanner = lexer.MustStateful(lexer.Rules{
@@ -91,6 +126,7 @@ var scanner = lexer.MustStateful(lexer.Rules{
 		{"Whitespace", `[ \t]+`, nil},
 		{"Comment", `[#].*`, nil},
 		{"String", `"(\\"|[^"])*"`, nil},
+		{"Float", `[-]?[0-9]+\.[0-9]+`, nil},
 		{"Int", `[-]?[0-9][0-9]*`, nil},
 		{"DOLLAR", `\$`, nil},
 		{"COLON", `\:`, nil},
@@ -110,5 +146,93 @@ var parser = participle.MustBuild[astScript](participle.Lexer(scanner),
 	participle.Elide("Whitespace", "Comment"))
 
 func parse(r io.Reader) (*astScript, error) {
-	return parser.Parse("test", r)
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	lex, err := scanner.LexString("test", string(src))
+	if err != nil {
+		return nil, err
+	}
+	toks, err := lexer.ConsumeAll(lex)
+	if err != nil {
+		return nil, err
+	}
+
+	// The tokens are kept so that the doc comments, which the parser elides, can be
+	// scanned without lexing the source again.
+	syms := scanner.Symbols()
+	peeker, err := lexer.Upgrade(&tokenLexer{toks: toks}, syms["Whitespace"], syms["Comment"])
+	if err != nil {
+		return nil, err
+	}
+	ast, err := parser.ParseFromLexer(peeker)
+	if err != nil {
+		return nil, err
+	}
+
+	ast.docComments = scanDocComments(src, toks)
+	return ast, nil
+}
+
+// tokenLexer is a lexer which returns tokens that have already been lexed. The last token
+// must be the EOF token.
+type tokenLexer struct {
+	toks []lexer.Token
+}
+
+func (tl *tokenLexer) Next() (lexer.Token, error) {
+	tok := tl.toks[0]
+	if len(tl.toks) > 1 {
+		tl.toks = tl.toks[1:]
+	}
+	return tok, nil
+}
+
+// IsIncomplete returns true if expr appears to end before it is complete, such as when a
+// block, list, sub-expression or string has not been closed. It can be used to decide
+// whether to prompt for more input.
+func IsIncomplete(expr string) bool {
+	lex, err := scanner.LexString("", expr)
+	if err != nil {
+		return false
+	}
+
+	syms := scanner.Symbols()
+	depth := 0
+	for {
+		tok, err := lex.Next()
+		if err != nil {
+			var lexErr *lexer.Error
+			return errors.As(err, &lexErr) && strings.HasPrefix(expr[lexErr.Pos.Offset:], `"`)
+		} else if tok.EOF() {
+			break
+		}
+
+		switch tok.Type {
+		case syms["LC"], syms["LP"], syms["LS"]:
+			depth++
+		case syms["RC"], syms["RP"], syms["RS"]:
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+func scanDocComments(src []byte, toks []lexer.Token) map[int]string {
+	comments := make(map[int]string)
+	commentType := scanner.Symbols()["Comment"]
+	for _, tok := range toks {
+		if tok.Type != commentType || strings.HasPrefix(tok.Value, "#!") {
+			continue
+		}
+
+		lineStart := bytes.LastIndexByte(src[:tok.Pos.Offset], '\n') + 1
+		if len(bytes.TrimSpace(src[lineStart:tok.Pos.Offset])) > 0 {
+			continue
+		}
+		comments[tok.Pos.Line] = strings.TrimSpace(strings.TrimPrefix(tok.Value, "#"))
+	}
+	return comments
 }
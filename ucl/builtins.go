@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func echoBuiltin(ctx context.Context, args invocationArgs) (object, error) {
@@ -65,6 +67,9 @@ func setBuiltin(ctx context.Context, args invocationArgs) (object, error) {
 
 	newVal := args.args[1]
 
+	if args.ec.isConst(name) {
+		return nil, errors.New("cannot set constant: " + name)
+	}
 	args.ec.setOrDefineVar(name, newVal)
 	return newVal, nil
 }
@@ -94,7 +99,25 @@ func eqBuiltin(ctx context.Context, args invocationArgs) (object, error) {
 			return boolObject(lv == rv), nil
 		}
 	case intObject:
-		if rv, ok := r.(intObject); ok {
+		switch rv := r.(type) {
+		case intObject:
+			return boolObject(lv == rv), nil
+		case floatObject:
+			return boolObject(float64(lv) == float64(rv)), nil
+		}
+	case floatObject:
+		switch rv := r.(type) {
+		case intObject:
+			return boolObject(float64(lv) == float64(rv)), nil
+		case floatObject:
+			return boolObject(lv == rv), nil
+		}
+	case timeObject:
+		if rv, ok := r.(timeObject); ok {
+			return boolObject(time.Time(lv).Equal(time.Time(rv))), nil
+		}
+	case durationObject:
+		if rv, ok := r.(durationObject); ok {
 			return boolObject(lv == rv), nil
 		}
 	}
@@ -204,9 +227,9 @@ func keysBuiltin(ctx context.Context, args invocationArgs) (object, error) {
 	val := args.args[0]
 	switch v := val.(type) {
 	case hashable:
-		keys := make(listObject, 0, v.Len())
+		keys := &listObject{elems: make([]object, 0, v.Len())}
 		if err := v.Each(func(k string, _ object) error {
-			keys = append(keys, strObject(k))
+			keys.Append(strObject(k))
 			return nil
 		}); err != nil {
 			return nil, err
@@ -217,6 +240,107 @@ func keysBuiltin(ctx context.Context, args invocationArgs) (object, error) {
 	return nil, nil
 }
 
+func setAtBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(3); err != nil {
+		return nil, err
+	}
+
+	key, val := args.args[1], args.args[2]
+
+	switch t := args.args[0].(type) {
+	case *hashObject:
+		strKey, ok := key.(strObject)
+		if !ok {
+			return nil, errors.New("expected string for hashable")
+		}
+		t.set(string(strKey), val)
+	case mapProxyObject:
+		strKey, ok := key.(strObject)
+		if !ok {
+			return nil, errors.New("expected string for hashable")
+		}
+		if err := t.setValue(args, string(strKey), val); err != nil {
+			return nil, err
+		}
+	case structProxyObject:
+		strKey, ok := key.(strObject)
+		if !ok {
+			return nil, errors.New("expected string for hashable")
+		}
+		if err := t.setValue(args, string(strKey), val); err != nil {
+			return nil, err
+		}
+	case *listObject:
+		idx, err := setAtIndex(key, t.Len())
+		if err != nil {
+			return nil, err
+		}
+		t.elems[idx] = val
+	case listableProxyObject:
+		idx, err := setAtIndex(key, t.Len())
+		if err != nil {
+			return nil, err
+		}
+
+		elem := t.v.Index(idx)
+		if !elem.CanSet() {
+			return nil, fmt.Errorf("elements of %v are not settable", t.v.Type())
+		}
+
+		ev, err := args.toGoReflectValue(val, elem.Type())
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", idx, err)
+		}
+		elem.Set(ev)
+	default:
+		return nil, fmt.Errorf("cannot set elements of %v", typeName(args.args[0]))
+	}
+
+	return val, nil
+}
+
+func setAtIndex(key object, l int) (int, error) {
+	intIdx, ok := key.(intObject)
+	if !ok {
+		return 0, errors.New("expected int for listable")
+	} else if int(intIdx) < 0 || int(intIdx) >= l {
+		return 0, fmt.Errorf("index %d out of range", int(intIdx))
+	}
+	return int(intIdx), nil
+}
+
+func appendBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(1); err != nil {
+		return nil, err
+	}
+
+	switch t := args.args[0].(type) {
+	case nil:
+		return &listObject{elems: append([]object{}, args.args[1:]...)}, nil
+	case *listObject:
+		newList := make([]object, 0, t.Len()+len(args.args)-1)
+		newList = append(newList, t.elems...)
+		return &listObject{elems: append(newList, args.args[1:]...)}, nil
+	case listableProxyObject:
+		newSlice := t.v
+		for i, a := range args.args[1:] {
+			ev, err := args.toGoReflectValue(a, t.v.Type().Elem())
+			if err != nil {
+				return nil, fmt.Errorf("arg %v of 'append': %w", i+1, err)
+			}
+			newSlice = reflect.Append(newSlice, ev)
+		}
+
+		// Slices reached through a pointer or a settable field are updated in place
+		if t.v.CanSet() {
+			t.v.Set(newSlice)
+			return t, nil
+		}
+		return listableProxyObject{v: newSlice, orig: newSlice}, nil
+	}
+	return nil, errors.New("expected listable")
+}
+
 func mapBuiltin(ctx context.Context, args invocationArgs) (object, error) {
 	if err := args.expectArgn(2); err != nil {
 		return nil, err
@@ -230,14 +354,27 @@ func mapBuiltin(ctx context.Context, args invocationArgs) (object, error) {
 	switch t := args.args[0].(type) {
 	case listable:
 		l := t.Len()
-		newList := listObject{}
+		newList := &listObject{}
 		for i := 0; i < l; i++ {
 			v := t.Index(i)
 			m, err := inv.invoke(ctx, args.fork([]object{v}))
 			if err != nil {
 				return nil, err
 			}
-			newList = append(newList, m)
+			newList.Append(m)
+		}
+		return newList, nil
+	case Iterator:
+		newList := &listObject{}
+		if err := t.each(ctx, func(v object) error {
+			m, err := inv.invoke(ctx, args.fork([]object{v}))
+			if err != nil {
+				return err
+			}
+			newList.Append(m)
+			return nil
+		}); err != nil {
+			return nil, err
 		}
 		return newList, nil
 	}
@@ -257,30 +394,43 @@ func filterBuiltin(ctx context.Context, args invocationArgs) (object, error) {
 	switch t := args.args[0].(type) {
 	case listable:
 		l := t.Len()
-		newList := listObject{}
+		newList := &listObject{}
 		for i := 0; i < l; i++ {
 			v := t.Index(i)
 			m, err := inv.invoke(ctx, args.fork([]object{v}))
 			if err != nil {
 				return nil, err
 			} else if m.Truthy() {
-				newList = append(newList, v)
+				newList.Append(v)
 			}
 		}
 		return newList, nil
 	case hashable:
-		newHash := hashObject{}
+		newHash := newHashObject(0)
 		if err := t.Each(func(k string, v object) error {
 			if m, err := inv.invoke(ctx, args.fork([]object{strObject(k), v})); err != nil {
 				return err
 			} else if m.Truthy() {
-				newHash[k] = v
+				newHash.set(k, v)
 			}
 			return nil
 		}); err != nil {
 			return nil, err
 		}
 		return newHash, nil
+	case Iterator:
+		newList := &listObject{}
+		if err := t.each(ctx, func(v object) error {
+			if m, err := inv.invoke(ctx, args.fork([]object{v})); err != nil {
+				return err
+			} else if m.Truthy() {
+				newList.Append(v)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		return newList, nil
 	}
 	return nil, errors.New("expected listable")
 }
@@ -329,6 +479,24 @@ func reduceBuiltin(ctx context.Context, args invocationArgs) (object, error) {
 			accum = newAccum
 		}
 		return accum, nil
+	case Iterator:
+		if err := t.each(ctx, func(v object) error {
+			if setFirst {
+				accum = v
+				setFirst = false
+				return nil
+			}
+
+			newAccum, err := block.invoke(ctx, args.fork([]object{v, accum}))
+			if err != nil {
+				return err
+			}
+			accum = newAccum
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		return accum, nil
 	case hashable:
 		// TODO: should raise error?
 		if err := t.Each(func(k string, v object) error {
@@ -357,6 +525,10 @@ func firstBuiltin(ctx context.Context, args invocationArgs) (object, error) {
 			return nil, nil
 		}
 		return t.Index(0), nil
+	case Iterator:
+		defer t.Close()
+		v, _, err := t.nextObject(ctx)
+		return v, err
 	}
 	return nil, errors.New("expected listable")
 }
@@ -488,6 +660,19 @@ func foreachBuiltin(ctx context.Context, args macroArgs) (object, error) {
 				}
 			}
 		}
+	case Iterator:
+		err := t.each(ctx, func(v object) error {
+			last, err = args.evalBlock(ctx, blockIdx, []object{v}, true)
+			if errors.As(err, &breakErr) && breakErr.isCont {
+				return nil
+			}
+			return err
+		})
+		if errors.As(err, &breakErr) {
+			return breakErr.ret, nil
+		} else if err != nil {
+			return nil, err
+		}
 	case hashable:
 		err := t.Each(func(k string, v object) error {
 			last, err = args.evalBlock(ctx, blockIdx, []object{strObject(k), v}, true)
@@ -523,6 +708,18 @@ func returnBuiltin(ctx context.Context, args invocationArgs) (object, error) {
 	return nil, errReturn{ret: args.args[0]}
 }
 
+func exitBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if len(args.args) < 1 {
+		return nil, ExitError{}
+	}
+
+	code, err := args.intArg(0)
+	if err != nil {
+		return nil, err
+	}
+	return nil, ExitError{Code: code}
+}
+
 func procBuiltin(ctx context.Context, args macroArgs) (object, error) {
 	if args.nargs() < 1 {
 		return nil, errors.New("need at least one arguments")
@@ -546,7 +743,12 @@ func procBuiltin(ctx context.Context, args macroArgs) (object, error) {
 		return nil, fmt.Errorf("malformed procedure: expected block object, was %v", block.String())
 	}
 
-	obj := procObject{args.eval, args.ec, blockObj.block}
+	var doc string
+	if args.eval.script != nil {
+		doc = args.eval.script.docComment(args.ast.Pos.Line)
+	}
+
+	obj := procObject{args.eval, args.ec, blockObj.block, doc}
 	if procName != "" {
 		args.ec.addCmd(procName, obj)
 	}
@@ -557,6 +759,7 @@ type procObject struct {
 	eval  evaluator
 	ec    *evalCtx
 	block *astBlock
+	doc   string
 }
 
 func (b procObject) String() string {
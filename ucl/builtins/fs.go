@@ -3,31 +3,194 @@ package builtins
 import (
 	"bufio"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
 	"ucl.lmika.dev/ucl"
 )
 
+var errReadOnlyFS = errors.New("file system is read-only")
+
 type fsHandlers struct {
-	fs fs.FS
+	fs        fs.FS
+	writeRoot string
+	sandboxed bool
+}
+
+// FSOption is an option of the fs module.
+type FSOption func(*fsHandlers)
+
+// FSWriteRoot sets the directory that functions which modify files, such as fs:write and
+// fs:rm, operate in. Paths are taken as relative to dir, and cannot be absolute, contain
+// "..", or lead outside dir through a symbolic link. To read and write the same directory,
+// pass os.DirFS(dir) to FS:
+//
+//	builtins.FS(os.DirFS(dir), builtins.FSWriteRoot(dir))
+func FSWriteRoot(dir string) FSOption {
+	return func(fh *fsHandlers) {
+		fh.writeRoot = dir
+	}
 }
 
-func FS(fs fs.FS) ucl.Module {
-	fsh := fsHandlers{fs: fs}
+// FS returns a module of file system functions. Files are read from fsys, or from the OS if
+// fsys is nil. Files can only be modified if fsys is nil or if a directory is set using
+// FSWriteRoot.
+func FS(fsys fs.FS, opts ...FSOption) ucl.Module {
+	fsh := fsHandlers{fs: fsys, sandboxed: fsys != nil}
+	if fsys == nil {
+		fsh.fs = osFS{}
+	}
+	for _, opt := range opts {
+		opt(&fsh)
+	}
+	if fsh.writeRoot != "" {
+		fsh.sandboxed = true
+	}
 
 	return ucl.Module{
 		Name: "fs",
 		Builtins: map[string]ucl.BuiltinHandler{
-			"lines": fsh.lines,
+			"lines":    fsh.lines,
+			"read":     ucl.Func(fsh.read),
+			"write":    ucl.Func(fsh.write),
+			"append":   ucl.Func(fsh.append),
+			"exists":   ucl.Func(fsh.exists),
+			"stat":     ucl.Func(fsh.stat),
+			"ls":       ucl.Func(fsh.ls),
+			"glob":     ucl.Func(fsh.glob),
+			"walk":     ucl.Func(fsh.walk),
+			"mkdir":    ucl.Func(fsh.mkdir),
+			"rm":       ucl.Func(fsh.rm),
+			"mv":       ucl.Func(fsh.mv),
+			"cp":       ucl.Func(fsh.cp),
+			"tempFile": ucl.Func(fsh.tempFile),
+			"tempDir":  ucl.Func(fsh.tempDir),
+		},
+		Docs: map[string]ucl.Doc{
+			"lines": {Description: "Returns the lines of a file as a list.", Args: []string{"FILE"}},
+			"read":  {Description: "Returns the contents of a file as a string.", Args: []string{"FILE"}},
+			"write": {
+				Description: "Writes CONTENT to a file, replacing it if it exists. If CONTENT is a list, each\nelement is written as a line.",
+				Args:        []string{"FILE", "CONTENT"},
+			},
+			"append": {Description: "Adds CONTENT to the end of a file, creating it if necessary. See write for CONTENT.", Args: []string{"FILE", "CONTENT"}},
+			"exists": {Description: "Returns true if a file or directory exists at PATH.", Args: []string{"PATH"}},
+			"stat": {
+				Description: "Returns a hash of the name, size, mode, modTime and isDir of the file at PATH.",
+				Args:        []string{"PATH"},
+			},
+			"ls": {
+				Description: "Returns the names of the entries of DIR, which defaults to the current directory, in\nname order.",
+				Args:        []string{"[DIR]"},
+				Switches:    []ucl.SwitchDoc{{Name: "long", Description: "Return the entries as hashes, as returned by stat"}},
+			},
+			"glob": {Description: "Returns the paths of the files matching PATTERN.", Args: []string{"PATTERN"}},
+			"walk": {
+				Description: "Returns an iterator of the paths of the files and directories under DIR, which defaults\nto the current directory. Directories are read as the iterator is consumed.",
+				Args:        []string{"[DIR]"},
+				Switches:    []ucl.SwitchDoc{{Name: "files", Description: "Only return the paths of files"}},
+			},
+			"mkdir": {Description: "Creates the directory DIR, along with any missing parents.", Args: []string{"DIR"}},
+			"rm": {
+				Description: "Removes the file or empty directory at PATH.",
+				Args:        []string{"PATH"},
+				Switches:    []ucl.SwitchDoc{{Name: "r", Description: "Remove directories and everything they contain"}},
+			},
+			"mv": {Description: "Moves or renames the file or directory at SRC to DEST.", Args: []string{"SRC", "DEST"}},
+			"cp": {Description: "Copies the file at SRC, which is read like any other file, to DEST.", Args: []string{"SRC", "DEST"}},
+			"tempFile": {
+				Description: "Creates a new, empty temporary file and returns its path. A \"*\" in PATTERN is replaced\nwith a random string. If files can only be modified within a directory, the file is\ncreated within it.",
+				Args:        []string{"[PATTERN]"},
+			},
+			"tempDir": {Description: "Creates a new temporary directory and returns its path. See tempFile for PATTERN.", Args: []string{"[PATTERN]"}},
 		},
 	}
 }
 
-func (fh fsHandlers) openFile(name string) (fs.File, error) {
-	if fh.fs == nil {
-		return os.Open(name)
+// writePath returns the path of name to be modified. An error is returned if files cannot
+// be modified, or name is outside the write root, including through a symbolic link.
+func (fh fsHandlers) writePath(name string) (string, error) {
+	switch {
+	case !fh.sandboxed:
+		return name, nil
+	case fh.writeRoot == "":
+		return "", &fs.PathError{Op: "write", Path: name, Err: errReadOnlyFS}
+	case !fs.ValidPath(name):
+		return "", &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+
+	p := filepath.Join(fh.writeRoot, filepath.FromSlash(name))
+	if within, err := fh.withinWriteRoot(p); err != nil {
+		return "", err
+	} else if !within {
+		return "", &fs.PathError{Op: "write", Path: name, Err: fs.ErrPermission}
+	}
+	return p, nil
+}
+
+// withinWriteRoot returns true if p is within the write root once any symbolic links have
+// been resolved.
+func (fh fsHandlers) withinWriteRoot(p string) (bool, error) {
+	root, err := resolvePath(fh.writeRoot)
+	if err != nil {
+		return false, err
+	}
+	rp, err := resolvePath(p)
+	if err != nil {
+		return false, err
+	}
+
+	rel, err := filepath.Rel(root, rp)
+	if err != nil {
+		return false, err
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
+
+// resolvePath returns the absolute path of p with any symbolic links resolved. Unlike
+// filepath.EvalSymlinks, p does not need to exist, with the elements which do not exist kept
+// as they are. Symbolic links to files which do not exist are also resolved, as they would
+// be followed when creating the file.
+func resolvePath(p string) (string, error) {
+	p, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+
+	var rest []string
+	for links := 0; ; {
+		rp, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return filepath.Join(append([]string{rp}, rest...)...), nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		if target, err := os.Readlink(p); err == nil {
+			// A link to a file which does not exist
+			if links++; links > 255 {
+				return "", &fs.PathError{Op: "resolve", Path: p, Err: errors.New("too many links")}
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(p), target)
+			}
+			p = target
+			continue
+		}
+
+		parent := filepath.Dir(p)
+		if parent == p {
+			return filepath.Join(append([]string{p}, rest...)...), nil
+		}
+		rest = append([]string{filepath.Base(p)}, rest...)
+		p = parent
 	}
-	return fh.fs.Open(name)
 }
 
 func (fh fsHandlers) lines(ctx context.Context, args ucl.CallArgs) (any, error) {
@@ -36,7 +199,7 @@ func (fh fsHandlers) lines(ctx context.Context, args ucl.CallArgs) (any, error)
 		return nil, err
 	}
 
-	f, err := fh.openFile(fname)
+	f, err := fh.fs.Open(fname)
 	if err != nil {
 		return nil, err
 	}
@@ -54,3 +217,325 @@ func (fh fsHandlers) lines(ctx context.Context, args ucl.CallArgs) (any, error)
 
 	return lines, nil
 }
+
+func (fh fsHandlers) read(name string) (string, error) {
+	bts, err := fs.ReadFile(fh.fs, name)
+	if err != nil {
+		return "", err
+	}
+	return string(bts), nil
+}
+
+func (fh fsHandlers) write(name string, content any) error {
+	return fh.writeFile(name, content, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+}
+
+func (fh fsHandlers) append(name string, content any) error {
+	return fh.writeFile(name, content, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+}
+
+func (fh fsHandlers) writeFile(name string, content any, flag int) error {
+	p, err := fh.writePath(name)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(p, flag, 0o644)
+	if err != nil {
+		return err
+	}
+
+	var str string
+	switch c := content.(type) {
+	case string:
+		str = c
+	case []any:
+		str = joinLines(c)
+	case nil:
+	default:
+		str = fmt.Sprint(c)
+	}
+
+	if _, err := io.WriteString(f, str); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (fh fsHandlers) exists(name string) (bool, error) {
+	if _, err := fs.Stat(fh.fs, name); errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (fh fsHandlers) stat(name string) (*ucl.OrderedHash, error) {
+	fi, err := fs.Stat(fh.fs, name)
+	if err != nil {
+		return nil, err
+	}
+	return fsStatHash(fi), nil
+}
+
+type fsLongSwitch struct {
+	Long bool `ucl:"-long"`
+}
+
+func (fh fsHandlers) ls(sw fsLongSwitch, dir ...string) ([]any, error) {
+	if len(dir) > 1 {
+		return nil, errors.New("expected at most 1 arg")
+	}
+
+	d := "."
+	if len(dir) == 1 {
+		d = dir[0]
+	}
+
+	entries, err := fs.ReadDir(fh.fs, d)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]any, len(entries))
+	for i, e := range entries {
+		if !sw.Long {
+			res[i] = e.Name()
+			continue
+		}
+
+		fi, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		res[i] = fsStatHash(fi)
+	}
+	return res, nil
+}
+
+func (fh fsHandlers) glob(pattern string) ([]any, error) {
+	matches, err := fs.Glob(fh.fs, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]any, len(matches))
+	for i, m := range matches {
+		res[i] = m
+	}
+	return res, nil
+}
+
+type fsWalkSwitch struct {
+	Files bool `ucl:"-files"`
+}
+
+// walk returns an iterator of the paths under a directory, in the same order as fs.WalkDir.
+// The paths yet to be visited are held in a stack, with a directory read when it is popped.
+func (fh fsHandlers) walk(sw fsWalkSwitch, dir ...string) (ucl.Iterator, error) {
+	if len(dir) > 1 {
+		return ucl.Iterator{}, errors.New("expected at most 1 arg")
+	}
+
+	root := "."
+	if len(dir) == 1 {
+		root = dir[0]
+	}
+
+	type pending struct {
+		path  string
+		isDir bool
+	}
+
+	stack := []pending{{path: root, isDir: true}}
+	visitedRoot := false
+
+	return ucl.NewIterator(func(ctx context.Context) (any, bool, error) {
+		for len(stack) > 0 {
+			p := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if p.isDir {
+				entries, err := fs.ReadDir(fh.fs, p.path)
+				if err != nil {
+					return nil, false, err
+				}
+				for i := len(entries) - 1; i >= 0; i-- {
+					stack = append(stack, pending{path: path.Join(p.path, entries[i].Name()), isDir: entries[i].IsDir()})
+				}
+			}
+
+			if !visitedRoot {
+				visitedRoot = true
+				continue
+			} else if p.isDir && sw.Files {
+				continue
+			}
+			return p.path, true, nil
+		}
+		return nil, false, nil
+	}), nil
+}
+
+func (fh fsHandlers) mkdir(name string) error {
+	p, err := fh.writePath(name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(p, 0o755)
+}
+
+type fsRecursiveSwitch struct {
+	Recursive bool `ucl:"-r"`
+}
+
+func (fh fsHandlers) rm(name string, sw fsRecursiveSwitch) error {
+	p, err := fh.writePath(name)
+	if err != nil {
+		return err
+	} else if fh.sandboxed && path.Clean(name) == "." {
+		return &fs.PathError{Op: "rm", Path: name, Err: fs.ErrPermission}
+	}
+
+	if sw.Recursive {
+		return os.RemoveAll(p)
+	}
+	return os.Remove(p)
+}
+
+func (fh fsHandlers) mv(src, dest string) error {
+	sp, err := fh.writePath(src)
+	if err != nil {
+		return err
+	}
+	dp, err := fh.writePath(dest)
+	if err != nil {
+		return err
+	}
+	return os.Rename(sp, dp)
+}
+
+func (fh fsHandlers) cp(src, dest string) error {
+	dp, err := fh.writePath(dest)
+	if err != nil {
+		return err
+	}
+
+	sf, err := fh.fs.Open(src)
+	if err != nil {
+		return err
+	}
+	defer sf.Close()
+
+	df, err := os.Create(dp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(df, sf); err != nil {
+		df.Close()
+		return err
+	}
+	return df.Close()
+}
+
+func (fh fsHandlers) tempFile(pattern ...string) (string, error) {
+	dir, err := fh.tempRoot()
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(dir, strings.Join(pattern, ""))
+	if err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return fh.tempPath(f.Name())
+}
+
+func (fh fsHandlers) tempDir(pattern ...string) (string, error) {
+	dir, err := fh.tempRoot()
+	if err != nil {
+		return "", err
+	}
+
+	p, err := os.MkdirTemp(dir, strings.Join(pattern, ""))
+	if err != nil {
+		return "", err
+	}
+	return fh.tempPath(p)
+}
+
+// tempRoot returns the directory temporary files are created in, which is the write root
+// if files can only be modified within it.
+func (fh fsHandlers) tempRoot() (string, error) {
+	if !fh.sandboxed {
+		return "", nil
+	} else if fh.writeRoot == "" {
+		return "", errReadOnlyFS
+	}
+	return fh.writeRoot, nil
+}
+
+// tempPath returns the path of a temporary file as it would be passed to the other
+// functions, which is relative to the write root if files can only be modified within it.
+func (fh fsHandlers) tempPath(p string) (string, error) {
+	if !fh.sandboxed {
+		return p, nil
+	}
+
+	rel, err := filepath.Rel(fh.writeRoot, p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// joinLines returns the elements of lines as a string, with each element followed by a newline.
+func joinLines(lines []any) string {
+	var sb strings.Builder
+	for _, l := range lines {
+		if l != nil {
+			fmt.Fprint(&sb, l)
+		}
+		sb.WriteRune('\n')
+	}
+	return sb.String()
+}
+
+func fsStatHash(fi fs.FileInfo) *ucl.OrderedHash {
+	h := ucl.NewOrderedHash()
+	h.Set("name", fi.Name())
+	h.Set("size", int(fi.Size()))
+	h.Set("mode", fi.Mode().String())
+	h.Set("modTime", fi.ModTime())
+	h.Set("isDir", fi.IsDir())
+	return h
+}
+
+// osFS is a file system which reads files from the OS, using paths as they are given. It is
+// used when the module is not given a file system.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osFS) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
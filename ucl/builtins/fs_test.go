@@ -2,7 +2,11 @@ package builtins_test
 
 import (
 	"context"
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 	"testing/fstest"
 	"ucl.lmika.dev/ucl"
@@ -35,3 +39,171 @@ func TestFS_Cat(t *testing.T) {
 		})
 	}
 }
+
+func TestFS_Files(t *testing.T) {
+	tests := []struct {
+		descr   string
+		eval    string
+		want    any
+		wantErr bool
+	}{
+		{descr: "read", eval: `fs:read "dir/a.txt"`, want: "alpha\n"},
+		{descr: "read missing", eval: `fs:read "nope.txt"`, wantErr: true},
+		{descr: "write", eval: `fs:write "new.txt" "hello" ; fs:read "new.txt"`, want: "hello"},
+		{descr: "write replaces", eval: `fs:write "dir/a.txt" "beta" ; fs:read "dir/a.txt"`, want: "beta"},
+		{descr: "write list", eval: `fs:write "new.txt" ["one" "two"] ; fs:lines "new.txt"`, want: []string{"one", "two"}},
+		{descr: "append", eval: `fs:append "dir/a.txt" "more" ; fs:read "dir/a.txt"`, want: "alpha\nmore"},
+		{descr: "append creates", eval: `fs:append "new.txt" ["x"] ; fs:append "new.txt" ["y"] ; fs:read "new.txt"`, want: "x\ny\n"},
+		{descr: "write outside root", eval: `fs:write "../escape.txt" "x"`, wantErr: true},
+		{descr: "write absolute", eval: `fs:write "/tmp/escape.txt" "x"`, wantErr: true},
+
+		{descr: "exists", eval: `[(fs:exists "dir/a.txt") (fs:exists "dir") (fs:exists "nope")]`, want: []any{true, true, false}},
+		{descr: "stat", eval: `fs:stat "dir/a.txt" | pick "name" "size" "isDir"`, want: map[string]any{"name": "a.txt", "size": 6, "isDir": false}},
+		{descr: "stat dir", eval: `fs:stat "dir" | index "isDir"`, want: true},
+		{descr: "stat modTime", eval: `fs:stat "dir/a.txt" | index "modTime" | time:before (time:now)`, want: true},
+		{descr: "stat missing", eval: `fs:stat "nope"`, wantErr: true},
+		{descr: "ls", eval: `fs:ls`, want: []any{"dir", "top.txt"}},
+		{descr: "ls dir", eval: `fs:ls "dir"`, want: []any{"a.txt", "b.md", "sub"}},
+		{descr: "ls long", eval: `fs:ls "dir" -long | map { |e| index $e "isDir" }`, want: []any{false, false, true}},
+		{descr: "glob", eval: `fs:glob "dir/*.txt"`, want: []any{"dir/a.txt"}},
+		{descr: "glob none", eval: `fs:glob "*.go"`, want: []any{}},
+		{descr: "glob is list", eval: `fs:glob "dir/*" | push "x" | len`, want: 4},
+		{descr: "walk", eval: `fs:walk | map { |p| $p }`, want: []any{"dir", "dir/a.txt", "dir/b.md", "dir/sub", "dir/sub/c.txt", "top.txt"}},
+		{descr: "walk dir files", eval: `fs:walk "dir" -files | map { |p| $p }`, want: []any{"dir/a.txt", "dir/b.md", "dir/sub/c.txt"}},
+		{descr: "walk lazy", eval: `fs:walk | take 2`, want: []any{"dir", "dir/a.txt"}},
+		{descr: "walk missing", eval: `fs:walk "nope" | map { |p| $p }`, wantErr: true},
+
+		{descr: "mkdir", eval: `fs:mkdir "x/y" ; fs:write "x/y/z.txt" "z" ; fs:walk "x" | map { |p| $p }`, want: []any{"x/y", "x/y/z.txt"}},
+		{descr: "mkdir exists", eval: `fs:mkdir "dir" ; fs:exists "dir"`, want: true},
+		{descr: "rm", eval: `fs:rm "top.txt" ; fs:exists "top.txt"`, want: false},
+		{descr: "rm non-empty dir", eval: `fs:rm "dir"`, wantErr: true},
+		{descr: "rm recursive", eval: `fs:rm "dir" -r ; fs:ls`, want: []any{"top.txt"}},
+		{descr: "rm root", eval: `fs:rm "." -r`, wantErr: true},
+		{descr: "mv", eval: `fs:mv "top.txt" "dir/moved.txt" ; [(fs:exists "top.txt") (fs:read "dir/moved.txt")]`, want: []any{false, "top\n"}},
+		{descr: "cp", eval: `fs:cp "top.txt" "dir/copy.txt" ; [(fs:read "top.txt") (fs:read "dir/copy.txt")]`, want: []any{"top\n", "top\n"}},
+		{descr: "cp missing", eval: `fs:cp "nope.txt" "copy.txt"`, wantErr: true},
+		{descr: "tempFile", eval: `set f (fs:tempFile "log-*.txt") ; fs:write $f "temp" ; [(fs:read $f) (strs:hasPrefix $f "log-")]`, want: []any{"temp", true}},
+		{descr: "tempDir", eval: `set d (fs:tempDir) ; fs:ls $d`, want: []any{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			dir := t.TempDir()
+			assert.NoError(t, os.MkdirAll(filepath.Join(dir, "dir", "sub"), 0o755))
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "dir", "a.txt"), []byte("alpha\n"), 0o644))
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "dir", "b.md"), []byte("bravo\n"), 0o644))
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "dir", "sub", "c.txt"), []byte("charlie\n"), 0o644))
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "top.txt"), []byte("top\n"), 0o644))
+
+			inst := ucl.New(
+				ucl.WithModule(builtins.FS(os.DirFS(dir), builtins.FSWriteRoot(dir))),
+				ucl.WithModule(builtins.Strs()),
+				ucl.WithModule(builtins.Time()),
+			)
+			res, err := inst.Eval(context.Background(), tt.eval)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+		})
+	}
+}
+
+func TestFS_WriteRootSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires symbolic links")
+	}
+
+	tests := []struct {
+		descr   string
+		eval    string
+		want    any
+		wantErr bool
+	}{
+		{descr: "write through dir link", eval: `fs:write "out/x.txt" "x"`, wantErr: true},
+		{descr: "write to file link", eval: `fs:write "outFile" "x"`, wantErr: true},
+		{descr: "append to file link", eval: `fs:append "outFile" "x"`, wantErr: true},
+		{descr: "write to dangling link", eval: `fs:write "dangling" "x"`, wantErr: true},
+		{descr: "mkdir through dir link", eval: `fs:mkdir "out/new"`, wantErr: true},
+		{descr: "rm through dir link", eval: `fs:rm "out/f.txt"`, wantErr: true},
+		{descr: "mv through dir link", eval: `fs:mv "top.txt" "out/top.txt"`, wantErr: true},
+		{descr: "cp through dir link", eval: `fs:cp "top.txt" "out/top.txt"`, wantErr: true},
+		{descr: "link within root", eval: `fs:write "in/n.txt" "n" ; fs:read "dir/n.txt"`, want: "n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			dir, outside := t.TempDir(), t.TempDir()
+			assert.NoError(t, os.Mkdir(filepath.Join(dir, "dir"), 0o755))
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "top.txt"), []byte("top\n"), 0o644))
+			assert.NoError(t, os.WriteFile(filepath.Join(outside, "f.txt"), []byte("outside\n"), 0o644))
+			assert.NoError(t, os.Symlink(outside, filepath.Join(dir, "out")))
+			assert.NoError(t, os.Symlink(filepath.Join(outside, "f.txt"), filepath.Join(dir, "outFile")))
+			assert.NoError(t, os.Symlink(filepath.Join(outside, "new.txt"), filepath.Join(dir, "dangling")))
+			assert.NoError(t, os.Symlink("dir", filepath.Join(dir, "in")))
+
+			inst := ucl.New(ucl.WithModule(builtins.FS(os.DirFS(dir), builtins.FSWriteRoot(dir))))
+			res, err := inst.Eval(context.Background(), tt.eval)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+
+			entries, err := os.ReadDir(outside)
+			assert.NoError(t, err)
+			assert.Len(t, entries, 1)
+			bts, err := os.ReadFile(filepath.Join(outside, "f.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, "outside\n", string(bts))
+		})
+	}
+}
+
+func TestFS_ReadOnly(t *testing.T) {
+	tests := []struct {
+		descr   string
+		eval    string
+		want    any
+		wantErr bool
+	}{
+		{descr: "read", eval: `fs:read "test.txt"`, want: "these\nare\nlines"},
+		{descr: "exists", eval: `fs:exists "test.txt"`, want: true},
+		{descr: "write", eval: `fs:write "test.txt" "x"`, wantErr: true},
+		{descr: "mkdir", eval: `fs:mkdir "x"`, wantErr: true},
+		{descr: "rm", eval: `fs:rm "test.txt"`, wantErr: true},
+		{descr: "tempFile", eval: `fs:tempFile`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			inst := ucl.New(ucl.WithModule(builtins.FS(testFS)))
+			res, err := inst.Eval(context.Background(), tt.eval)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+		})
+	}
+}
+
+func TestFS_OS(t *testing.T) {
+	dir := t.TempDir()
+	inst := ucl.New(ucl.WithModule(builtins.FS(nil)))
+
+	res, err := inst.Eval(context.Background(), fmt.Sprintf(`
+		set dir %q
+		set f (cat $dir "/out.txt")
+		fs:write $f "written"
+		set t (fs:tempFile)
+		fs:rm $t
+		[(fs:read $f) (fs:ls $dir) (fs:exists $t)]
+	`, dir))
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"written", []any{"out.txt"}, false}, res)
+}
@@ -0,0 +1,389 @@
+package builtins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ucl.lmika.dev/ucl"
+)
+
+type jsonHandlers struct {
+}
+
+func JSON() ucl.Module {
+	jh := jsonHandlers{}
+
+	return ucl.Module{
+		Name: "json",
+		Builtins: map[string]ucl.BuiltinHandler{
+			"decode":      jh.decode,
+			"decodeLines": jh.decodeLines,
+			"encode":      jh.encode,
+			"path":        jh.path,
+		},
+		Docs: map[string]ucl.Doc{
+			"decode": {
+				Description: "Decodes a JSON string. Objects are returned as hashes, arrays as lists, and\nnumbers as ints or floats. Objects keep the order of their keys if the\ninstance has ordered hashes.",
+				Args:        []string{"STR"},
+			},
+			"decodeLines": {
+				Description: "Returns an iterator of the JSON values of SOURCE, which can be a string or a reader.\nValues are decoded as they are consumed.",
+				Args:        []string{"SOURCE"},
+			},
+			"encode": {
+				Description: "Encodes VALUE as a JSON string.",
+				Args:        []string{"VALUE"},
+				Switches:    []ucl.SwitchDoc{{Name: "pretty", Description: "Indent the JSON over multiple lines"}},
+			},
+			"path": {
+				Description: "Returns the element of VALUE at PATH, such as \"$.items[0].name\", or nil if it does not exist.\nA wildcard, \"*\" or \"[*]\", matches every element and returns a list of the matches.",
+				Args:        []string{"VALUE", "PATH"},
+			},
+		},
+	}
+}
+
+func (jh jsonHandlers) decode(ctx context.Context, args ucl.CallArgs) (any, error) {
+	var s string
+	if err := args.Bind(&s); err != nil {
+		return nil, err
+	}
+
+	dec := newJSONDecoder(strings.NewReader(s))
+
+	v, err := decodeJSON(dec, args.OrderedHashes())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+func (jh jsonHandlers) decodeLines(ctx context.Context, args ucl.CallArgs) (any, error) {
+	var src any
+	if err := args.Bind(&src); err != nil {
+		return nil, err
+	}
+
+	var r io.Reader
+	switch t := src.(type) {
+	case string:
+		r = strings.NewReader(t)
+	case io.Reader:
+		r = t
+	default:
+		return nil, fmt.Errorf("expected string or reader but was %T", src)
+	}
+
+	dec := newJSONDecoder(r)
+	ordered := args.OrderedHashes()
+	return ucl.NewIterator(func(ctx context.Context) (any, bool, error) {
+		v, err := decodeJSON(dec, ordered)
+		if errors.Is(err, io.EOF) {
+			return nil, false, nil
+		} else if err != nil {
+			return nil, false, err
+		}
+		return v, true, nil
+	}), nil
+}
+
+func (jh jsonHandlers) encode(ctx context.Context, args ucl.CallArgs) (any, error) {
+	var (
+		v      any
+		pretty bool
+	)
+	if err := args.Bind(&v); err != nil {
+		return nil, err
+	}
+	if err := args.BindSwitch("pretty", &pretty); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if pretty {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func (jh jsonHandlers) path(ctx context.Context, args ucl.CallArgs) (any, error) {
+	var (
+		v    any
+		path string
+	)
+	if err := args.Bind(&v, &path); err != nil {
+		return nil, err
+	}
+
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []reflect.Value{reflect.ValueOf(v)}
+	hasWildcard := false
+	for _, step := range steps {
+		hasWildcard = hasWildcard || step.wildcard
+
+		var next []reflect.Value
+		for _, m := range matches {
+			next = append(next, step.apply(m)...)
+		}
+		matches = next
+	}
+
+	if hasWildcard {
+		res := make([]any, len(matches))
+		for i, m := range matches {
+			res[i] = m.Interface()
+		}
+		return res, nil
+	} else if len(matches) == 0 || !matches[0].IsValid() {
+		return nil, nil
+	}
+	return matches[0].Interface(), nil
+}
+
+func newJSONDecoder(r io.Reader) *json.Decoder {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return dec
+}
+
+// decodeJSON decodes the next JSON value from dec. If ordered is true, objects are decoded
+// into an *OrderedHash so that the order of their keys is kept.
+func decodeJSON(dec *json.Decoder, ordered bool) (any, error) {
+	if !ordered {
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		return normaliseJSON(v), nil
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			h := ucl.NewOrderedHash()
+			for dec.More() {
+				kt, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				v, err := decodeJSON(dec, ordered)
+				if err != nil {
+					return nil, err
+				}
+				h.Set(kt.(string), v)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return h, nil
+		case '[':
+			l := []any{}
+			for dec.More() {
+				v, err := decodeJSON(dec, ordered)
+				if err != nil {
+					return nil, err
+				}
+				l = append(l, v)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return l, nil
+		}
+		return nil, fmt.Errorf("unexpected '%v'", t)
+	}
+	return normaliseJSON(tok), nil
+}
+
+// normaliseJSON converts the numbers of a decoded JSON value to ints, or floats if they are
+// not integers.
+func normaliseJSON(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(t), 10, 0); err == nil {
+			return int(i)
+		}
+		f, _ := t.Float64()
+		return f
+	case []any:
+		for i, e := range t {
+			t[i] = normaliseJSON(e)
+		}
+	case map[string]any:
+		for k, e := range t {
+			t[k] = normaliseJSON(e)
+		}
+	}
+	return v
+}
+
+type jsonPathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses a path made up of keys and indices, such as "$.items[0].name" or
+// "items.*.name". The leading "$" is optional.
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	var steps []jsonPathStep
+
+	p := strings.TrimPrefix(path, "$")
+	for p != "" {
+		switch {
+		case p[0] == '.':
+			p = p[1:]
+		case p[0] == '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path '%v': missing ']'", path)
+			}
+
+			sel := strings.TrimSpace(p[1:end])
+			p = p[end+1:]
+			switch {
+			case sel == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			case strings.HasPrefix(sel, `"`) || strings.HasPrefix(sel, `'`):
+				key, err := strconv.Unquote(`"` + strings.Trim(sel, `"'`) + `"`)
+				if err != nil {
+					return nil, fmt.Errorf("invalid path '%v': %w", path, err)
+				}
+				steps = append(steps, jsonPathStep{key: key})
+			default:
+				idx, err := strconv.Atoi(sel)
+				if err != nil {
+					return nil, fmt.Errorf("invalid path '%v': expected index but was '%v'", path, sel)
+				}
+				steps = append(steps, jsonPathStep{index: idx, isIndex: true})
+			}
+		default:
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			if key := p[:end]; key == "*" {
+				steps = append(steps, jsonPathStep{wildcard: true})
+			} else {
+				steps = append(steps, jsonPathStep{key: key})
+			}
+			p = p[end:]
+		}
+	}
+	return steps, nil
+}
+
+// apply returns the elements of v selected by the step.
+func (s jsonPathStep) apply(v reflect.Value) []reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer) {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if v.IsValid() && v.Type() == reflect.TypeOf(ucl.OrderedHash{}) {
+		h := v.Interface().(ucl.OrderedHash)
+		switch {
+		case s.wildcard:
+			res := make([]reflect.Value, 0, h.Len())
+			for _, k := range h.Keys() {
+				e, _ := h.Get(k)
+				res = append(res, reflect.ValueOf(&e).Elem())
+			}
+			return res
+		case !s.isIndex:
+			// Values are taken through a pointer so that nil values remain valid
+			if e, ok := h.Get(s.key); ok {
+				return []reflect.Value{reflect.ValueOf(&e).Elem()}
+			}
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		switch {
+		case s.wildcard:
+			res := make([]reflect.Value, v.Len())
+			for i := range res {
+				res[i] = v.Index(i)
+			}
+			return res
+		case s.isIndex:
+			idx := s.index
+			if idx < 0 {
+				idx += v.Len()
+			}
+			if idx >= 0 && idx < v.Len() {
+				return []reflect.Value{v.Index(idx)}
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		switch {
+		case s.wildcard:
+			keys := v.MapKeys()
+			res := make([]reflect.Value, len(keys))
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+			for i, k := range keys {
+				res[i] = v.MapIndex(k)
+			}
+			return res
+		case !s.isIndex:
+			if e := v.MapIndex(reflect.ValueOf(s.key).Convert(v.Type().Key())); e.IsValid() {
+				return []reflect.Value{e}
+			}
+		}
+	case reflect.Struct:
+		var res []reflect.Value
+		for _, f := range reflect.VisibleFields(v.Type()) {
+			if !f.IsExported() || f.Anonymous {
+				continue
+			}
+
+			name := f.Name
+			if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag != "" {
+				name = tag
+			}
+			if name == "-" || (!s.wildcard && name != s.key && f.Name != s.key) {
+				continue
+			}
+
+			if fv, err := v.FieldByIndexErr(f.Index); err == nil {
+				res = append(res, fv)
+			}
+		}
+		return res
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package builtins_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ucl.lmika.dev/ucl"
+	"ucl.lmika.dev/ucl/builtins"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSON_Decode(t *testing.T) {
+	tests := []struct {
+		descr   string
+		eval    string
+		want    any
+		wantErr bool
+	}{
+		{descr: "object", eval: `json:decode '{"name":"test","count":3,"ratio":1.5,"ok":true,"none":null}'`, want: map[string]any{
+			"name": "test", "count": 3, "ratio": 1.5, "ok": true, "none": nil,
+		}},
+		{descr: "array", eval: `json:decode '[1, "two", [3]]'`, want: []any{1, "two", []any{3}}},
+		{descr: "scalar", eval: `json:decode '"hello"'`, want: "hello"},
+		{descr: "large number", eval: `json:decode '12345678901234567890'`, want: 12345678901234567890.0},
+		{descr: "decoded hash is native", eval: `set x (json:decode '{"a":{"b":1}}') ; set-at $x.a "c" 2 ; $x`, want: map[string]any{
+			"a": map[string]any{"b": 1, "c": 2},
+		}},
+		{descr: "via pipe", eval: `json:decode '[1,2,3]' | map { |x| add $x 1 }`, want: []any{2, 3, 4}},
+		{descr: "invalid", eval: `json:decode '{"a":'`, wantErr: true},
+		{descr: "trailing data", eval: `json:decode '1 2'`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			inst := ucl.New(ucl.WithModule(builtins.JSON()))
+			res, err := inst.Eval(context.Background(), jsonQuotes(tt.eval))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+		})
+	}
+
+	t.Run("ordered hashes", func(t *testing.T) {
+		inst := ucl.New(ucl.WithModule(builtins.JSON()), ucl.WithOrderedHashes())
+
+		res, err := inst.Eval(context.Background(), jsonQuotes(`json:decode '{"z":1,"a":{"y":2,"b":null},"m":[{"q":1.5,"c":true}]}'`))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"z", "a", "m"}, res.(*ucl.OrderedHash).Keys())
+
+		res, err = inst.Eval(context.Background(), jsonQuotes(`json:encode (json:decode '{"z":1,"a":{"y":2,"b":null},"m":[{"q":1.5,"c":true}]}')`))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"z":1,"a":{"y":2,"b":null},"m":[{"q":1.5,"c":true}]}`, res)
+
+		res, err = inst.Eval(context.Background(), jsonQuotes(`json:decodeLines '{"b":1,"a":2} {"d":3,"c":4}' | map { |v| json:encode $v }`))
+		assert.NoError(t, err)
+		assert.Equal(t, []any{`{"b":1,"a":2}`, `{"d":3,"c":4}`}, res)
+
+		for _, expr := range []string{`json:decode '{"a":'`, `json:decode '1 2'`, `json:decode '{"a":1]'`} {
+			_, err := inst.Eval(context.Background(), jsonQuotes(expr))
+			assert.Error(t, err, expr)
+		}
+	})
+}
+
+func TestJSON_DecodeLines(t *testing.T) {
+	tests := []struct {
+		descr   string
+		eval    string
+		want    any
+		wantErr bool
+	}{
+		{descr: "foreach", eval: `
+			set names []
+			foreach (json:decodeLines $lines) { |v| set names (append $names $v.name) }
+			$names
+		`, want: []any{"a", "b", "c"}},
+		{descr: "map", eval: `json:decodeLines $lines | map { |v| $v.n }`, want: []any{1, 2, 3}},
+		{descr: "filter", eval: `json:decodeLines $lines | filter { |v| eq $v.n 2 } | map { |v| $v.name }`, want: []any{"b"}},
+		{descr: "reduce", eval: `json:decodeLines $lines | map { |v| $v.n } | reduce { |x a| add $x $a }`, want: 6},
+		{descr: "head", eval: `(json:decodeLines $lines | head).name`, want: "a"},
+		{descr: "break", eval: `foreach (json:decodeLines $lines) { |v| if (eq $v.n 2) { break $v.name } }`, want: "b"},
+		{descr: "reader", eval: `json:decodeLines $reader | map { |v| $v.n }`, want: []any{1, 2, 3}},
+		{descr: "error", eval: `json:decodeLines "{} {bad" | map { |v| $v }`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			lines := "{\"name\":\"a\",\"n\":1}\n{\"name\":\"b\",\"n\":2}\n\n{\"name\":\"c\",\"n\":3}\n"
+
+			inst := ucl.New(ucl.WithModule(builtins.JSON()))
+			inst.SetFunc("lines", func() string { return lines })
+			inst.SetFunc("reader", func() ucl.OpaqueObject { return ucl.Opaque(strings.NewReader(lines)) })
+
+			_, err := inst.Eval(context.Background(), `set lines (lines) ; set reader (reader)`)
+			assert.NoError(t, err)
+
+			res, err := inst.Eval(context.Background(), tt.eval)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+		})
+	}
+}
+
+func TestJSON_Encode(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+	type person struct {
+		Name    string
+		Age     int
+		Address address `json:"address"`
+	}
+
+	tests := []struct {
+		descr string
+		eval  string
+		want  any
+	}{
+		{descr: "hash", eval: `json:encode [b:[1 2] a:"x<y" c:(true) d:() e:1.5]`, want: `{"a":"x<y","b":[1,2],"c":true,"d":null,"e":1.5}`},
+		{descr: "string", eval: `json:encode "hello"`, want: `"hello"`},
+		{descr: "empty", eval: `json:encode [[] [:]]`, want: `[[],{}]`},
+		{descr: "pretty", eval: `json:encode [a:[1]] -pretty`, want: "{\n  \"a\": [\n    1\n  ]\n}"},
+		{descr: "struct proxy", eval: `json:encode (person)`, want: `{"Name":"Alice","Age":30,"address":{"city":"Paris"}}`},
+		{descr: "round trip", eval: `json:encode (json:decode (json:encode [a:[1 "2" [b:()]]]))`, want: `{"a":[1,"2",{"b":null}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			inst := ucl.New(ucl.WithModule(builtins.JSON()))
+			inst.SetFunc("person", func() person {
+				return person{Name: "Alice", Age: 30, Address: address{City: "Paris"}}
+			})
+
+			res, err := inst.Eval(context.Background(), tt.eval)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestJSON_Path(t *testing.T) {
+	doc := `{"items":[{"name":"a","tags":["x","y"]},{"name":"b","tags":[]}],"meta":{"total":2,"odd key":true}}`
+
+	tests := []struct {
+		descr   string
+		path    string
+		want    any
+		wantErr bool
+	}{
+		{descr: "key", path: "meta.total", want: 2},
+		{descr: "leading dollar", path: "$.meta.total", want: 2},
+		{descr: "index", path: "$.items[1].name", want: "b"},
+		{descr: "negative index", path: "items[-1].name", want: "b"},
+		{descr: "quoted key", path: `meta["odd key"]`, want: true},
+		{descr: "wildcard", path: "items[*].name", want: []any{"a", "b"}},
+		{descr: "nested wildcard", path: "items.*.tags.*", want: []any{"x", "y"}},
+		{descr: "hash wildcard", path: "meta.*", want: []any{true, 2}},
+		{descr: "missing key", path: "meta.missing", want: nil},
+		{descr: "index out of range", path: "items[5]", want: nil},
+		{descr: "hash", path: "$.meta", want: map[string]any{"total": 2, "odd key": true}},
+		{descr: "invalid index", path: "items[x]", wantErr: true},
+		{descr: "unterminated", path: "items[0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			inst := ucl.New(ucl.WithModule(builtins.JSON()))
+			inst.SetFunc("doc", func() string { return doc })
+
+			res, err := inst.Eval(context.Background(), `json:decode (doc) | json:path `+strconvQuote(tt.path))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+
+	t.Run("struct proxy", func(t *testing.T) {
+		type item struct {
+			Name  string `json:"name"`
+			Count int
+		}
+
+		inst := ucl.New(ucl.WithModule(builtins.JSON()))
+		inst.SetFunc("items", func() []item { return []item{{Name: "a", Count: 1}, {Name: "b", Count: 2}} })
+
+		res, err := inst.Eval(context.Background(), `json:path (items) "[*].name"`)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"a", "b"}, res)
+
+		res, err = inst.Eval(context.Background(), `json:path (items) "[1].Count"`)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, res)
+	})
+
+	t.Run("nil document", func(t *testing.T) {
+		inst := ucl.New(ucl.WithModule(builtins.JSON()))
+
+		for _, expr := range []string{`json:path () "$"`, `json:path () ()`, `json:path () "a.b"`, `json:path (json:decode "null") "$"`} {
+			res, err := inst.Eval(context.Background(), expr)
+			assert.NoError(t, err, expr)
+			assert.Nil(t, res, expr)
+		}
+	})
+
+	t.Run("ordered hashes", func(t *testing.T) {
+		inst := ucl.New(ucl.WithModule(builtins.JSON()), ucl.WithOrderedHashes())
+		inst.SetFunc("doc", func() string { return doc })
+
+		res, err := inst.Eval(context.Background(), `json:path [a:[b:1]] "a.b"`)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res)
+
+		res, err = inst.Eval(context.Background(), `json:decode (doc) | json:path "$.items[1].name"`)
+		assert.NoError(t, err)
+		assert.Equal(t, "b", res)
+
+		res, err = inst.Eval(context.Background(), `json:path [z:1 a:2] "*"`)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{1, 2}, res)
+
+		res, err = inst.Eval(context.Background(), `json:path [a:[b:1]] "a.missing"`)
+		assert.NoError(t, err)
+		assert.Nil(t, res)
+
+		res, err = inst.Eval(context.Background(), `json:path [a:()] "a"`)
+		assert.NoError(t, err)
+		assert.Nil(t, res)
+
+		res, err = inst.Eval(context.Background(), `json:path [a:() b:1] "*"`)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{nil, 1}, res)
+
+		res, err = inst.Eval(context.Background(), jsonQuotes(`json:decode '{"a":{"b":null}}' | json:path "$.a.b"`))
+		assert.NoError(t, err)
+		assert.Nil(t, res)
+	})
+}
+
+// jsonQuotes replaces single quotes with double quotes, escaping any double quotes within
+// them, so that JSON can be written within test expressions without escaping.
+func jsonQuotes(s string) string {
+	var sb strings.Builder
+	inQuote := false
+	for _, r := range s {
+		switch {
+		case r == '\'':
+			inQuote = !inQuote
+			sb.WriteRune('"')
+		case r == '"' && inQuote:
+			sb.WriteString(`\"`)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func strconvQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,421 @@
+package builtins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"ucl.lmika.dev/ucl"
+)
+
+var errIntOverflow = errors.New("integer overflow")
+
+type mathHandlers struct {
+}
+
+// Math returns a module of numeric functions. Functions which accept ints and floats return
+// an int if all the arguments are ints, and a float otherwise. Integer arithmetic returns an
+// error on overflow, rather than wrapping around.
+//
+// Random numbers are taken from the generator of the instance, which can be seeded using
+// ucl.WithRandSeed.
+func Math() ucl.Module {
+	mh := mathHandlers{}
+
+	return ucl.Module{
+		Name: "math",
+		Builtins: map[string]ucl.BuiltinHandler{
+			"add":    ucl.Func(mh.add),
+			"sub":    ucl.Func(mh.sub),
+			"mul":    ucl.Func(mh.mul),
+			"div":    ucl.Func(mh.div),
+			"idiv":   ucl.Func(mh.idiv),
+			"mod":    ucl.Func(mh.mod),
+			"abs":    ucl.Func(mh.abs),
+			"min":    ucl.Func(mh.min),
+			"max":    ucl.Func(mh.max),
+			"clamp":  ucl.Func(mh.clamp),
+			"floor":  ucl.Func(mh.roundFunc(math.Floor)),
+			"ceil":   ucl.Func(mh.roundFunc(math.Ceil)),
+			"trunc":  ucl.Func(mh.roundFunc(math.Trunc)),
+			"round":  ucl.Func(mh.round),
+			"pow":    ucl.Func(mh.pow),
+			"sqrt":   ucl.Func(math.Sqrt),
+			"exp":    ucl.Func(math.Exp),
+			"log":    ucl.Func(mh.log),
+			"log10":  ucl.Func(math.Log10),
+			"log2":   ucl.Func(math.Log2),
+			"sin":    ucl.Func(math.Sin),
+			"cos":    ucl.Func(math.Cos),
+			"tan":    ucl.Func(math.Tan),
+			"asin":   ucl.Func(math.Asin),
+			"acos":   ucl.Func(math.Acos),
+			"atan":   ucl.Func(math.Atan),
+			"atan2":  ucl.Func(math.Atan2),
+			"pi":     ucl.Func(func() float64 { return math.Pi }),
+			"e":      ucl.Func(func() float64 { return math.E }),
+			"inf":    ucl.Func(func() float64 { return math.Inf(1) }),
+			"maxInt": ucl.Func(func() int { return math.MaxInt }),
+			"minInt": ucl.Func(func() int { return math.MinInt }),
+			"rand":   mh.rand,
+		},
+		Docs: map[string]ucl.Doc{
+			"add":    {Description: "Returns the sum of the numbers.", Args: []string{"NUMS..."}},
+			"sub":    {Description: "Returns N with each of the numbers subtracted.", Args: []string{"N", "NUMS..."}},
+			"mul":    {Description: "Returns the product of the numbers.", Args: []string{"NUMS..."}},
+			"div":    {Description: "Returns N divided by M as a float.", Args: []string{"N", "M"}},
+			"idiv":   {Description: "Returns the int N divided by M, rounded down. M cannot be 0.", Args: []string{"N", "M"}},
+			"mod":    {Description: "Returns the remainder of the int N divided by M, rounded down, which has the\nsame sign as M. M cannot be 0.", Args: []string{"N", "M"}},
+			"abs":    {Description: "Returns the absolute value of N.", Args: []string{"N"}},
+			"min":    {Description: "Returns the smallest of the numbers. Lists of numbers can also be given.", Args: []string{"NUMS..."}},
+			"max":    {Description: "Returns the largest of the numbers. Lists of numbers can also be given.", Args: []string{"NUMS..."}},
+			"clamp":  {Description: "Returns N limited to between LO and HI inclusive.", Args: []string{"N", "LO", "HI"}},
+			"floor":  {Description: "Returns N rounded down to an int.", Args: []string{"N"}},
+			"ceil":   {Description: "Returns N rounded up to an int.", Args: []string{"N"}},
+			"trunc":  {Description: "Returns N rounded towards zero to an int.", Args: []string{"N"}},
+			"round":  {Description: "Returns N rounded half away from zero to an int, or to a float with PLACES decimal places.", Args: []string{"N", "[PLACES]"}},
+			"pow":    {Description: "Returns N raised to the power of M.", Args: []string{"N", "M"}},
+			"sqrt":   {Description: "Returns the square root of N.", Args: []string{"N"}},
+			"exp":    {Description: "Returns e raised to the power of N.", Args: []string{"N"}},
+			"log":    {Description: "Returns the natural logarithm of N, or the logarithm in BASE.", Args: []string{"N", "[BASE]"}},
+			"log10":  {Description: "Returns the base 10 logarithm of N.", Args: []string{"N"}},
+			"log2":   {Description: "Returns the base 2 logarithm of N.", Args: []string{"N"}},
+			"sin":    {Description: "Returns the sine of N radians.", Args: []string{"N"}},
+			"cos":    {Description: "Returns the cosine of N radians.", Args: []string{"N"}},
+			"tan":    {Description: "Returns the tangent of N radians.", Args: []string{"N"}},
+			"asin":   {Description: "Returns the arcsine of N in radians.", Args: []string{"N"}},
+			"acos":   {Description: "Returns the arccosine of N in radians.", Args: []string{"N"}},
+			"atan":   {Description: "Returns the arctangent of N in radians.", Args: []string{"N"}},
+			"atan2":  {Description: "Returns the arctangent of Y/X in radians, using the signs of both to find the quadrant.", Args: []string{"Y", "X"}},
+			"pi":     {Description: "Returns the constant pi."},
+			"e":      {Description: "Returns the constant e."},
+			"inf":    {Description: "Returns positive infinity."},
+			"maxInt": {Description: "Returns the largest int."},
+			"minInt": {Description: "Returns the smallest int."},
+			"rand": {
+				Description: "Returns a random float between 0 and 1. With N, returns a random int from 0 up to but\nnot including N. With LO and HI, returns a random int from LO up to but not including HI.",
+				Args:        []string{"[LO]", "[N|HI]"},
+			},
+		},
+	}
+}
+
+// mathNum is a number argument, which is either an int or a float.
+type mathNum struct {
+	i       int
+	f       float64
+	isFloat bool
+}
+
+func mathNumOf(v any) (mathNum, error) {
+	switch n := v.(type) {
+	case int:
+		return mathNum{i: n, f: float64(n)}, nil
+	case float64:
+		return mathNum{f: n, isFloat: true}, nil
+	}
+	return mathNum{}, fmt.Errorf("expected a number but was %T", v)
+}
+
+// mathNums converts the arguments to numbers. Lists are expanded if expandLists is true.
+func mathNums(args []any, expandLists bool) ([]mathNum, error) {
+	nums := make([]mathNum, 0, len(args))
+	for _, a := range args {
+		if l, ok := a.([]any); ok && expandLists {
+			ln, err := mathNums(l, false)
+			if err != nil {
+				return nil, err
+			}
+			nums = append(nums, ln...)
+			continue
+		}
+
+		n, err := mathNumOf(a)
+		if err != nil {
+			return nil, err
+		}
+		nums = append(nums, n)
+	}
+	return nums, nil
+}
+
+func (n mathNum) value() any {
+	if n.isFloat {
+		return n.f
+	}
+	return n.i
+}
+
+// mathFold applies the int or float operation to the numbers in turn, starting with the first
+// number. The float operation is used from the first float onwards.
+func mathFold(args []any, intOp func(a, b int) (int, error), floatOp func(a, b float64) float64) (any, error) {
+	nums, err := mathNums(args, false)
+	if err != nil {
+		return nil, err
+	} else if len(nums) == 0 {
+		return nil, errors.New("expected at least 1 number")
+	}
+
+	acc := nums[0]
+	for _, n := range nums[1:] {
+		if acc.isFloat || n.isFloat {
+			acc = mathNum{f: floatOp(acc.f, n.f), isFloat: true}
+			continue
+		}
+
+		r, err := intOp(acc.i, n.i)
+		if err != nil {
+			return nil, err
+		}
+		acc = mathNum{i: r, f: float64(r)}
+	}
+	return acc.value(), nil
+}
+
+func (mh mathHandlers) add(nums ...any) (any, error) {
+	return mathFold(nums, addInt, func(a, b float64) float64 { return a + b })
+}
+
+func (mh mathHandlers) sub(nums ...any) (any, error) {
+	return mathFold(nums, func(a, b int) (int, error) {
+		if b == math.MinInt {
+			if a >= 0 {
+				return 0, errIntOverflow
+			}
+			return a - b, nil
+		}
+		return addInt(a, -b)
+	}, func(a, b float64) float64 { return a - b })
+}
+
+func (mh mathHandlers) mul(nums ...any) (any, error) {
+	return mathFold(nums, mulInt, func(a, b float64) float64 { return a * b })
+}
+
+func (mh mathHandlers) div(n, m float64) float64 {
+	return n / m
+}
+
+func (mh mathHandlers) idiv(n, m int) (int, error) {
+	if m == 0 {
+		return 0, errors.New("division by zero")
+	} else if n == math.MinInt && m == -1 {
+		return 0, errIntOverflow
+	}
+
+	q := n / m
+	if (n%m != 0) && ((n < 0) != (m < 0)) {
+		q--
+	}
+	return q, nil
+}
+
+func (mh mathHandlers) mod(n, m int) (int, error) {
+	if m == 0 {
+		return 0, errors.New("division by zero")
+	} else if m == -1 {
+		return 0, nil
+	}
+
+	r := n % m
+	if r != 0 && ((r < 0) != (m < 0)) {
+		r += m
+	}
+	return r, nil
+}
+
+func (mh mathHandlers) abs(v any) (any, error) {
+	n, err := mathNumOf(v)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case n.isFloat:
+		return math.Abs(n.f), nil
+	case n.i == math.MinInt:
+		return nil, errIntOverflow
+	case n.i < 0:
+		return -n.i, nil
+	}
+	return n.i, nil
+}
+
+func (mh mathHandlers) min(nums ...any) (any, error) {
+	return mathExtreme(nums, -1)
+}
+
+func (mh mathHandlers) max(nums ...any) (any, error) {
+	return mathExtreme(nums, 1)
+}
+
+// mathExtreme returns the number for which comparing it to the others returns want.
+func mathExtreme(args []any, want int) (any, error) {
+	nums, err := mathNums(args, true)
+	if err != nil {
+		return nil, err
+	} else if len(nums) == 0 {
+		return nil, errors.New("expected at least 1 number")
+	}
+
+	best := nums[0]
+	for _, n := range nums[1:] {
+		if mathCompare(n, best) == want {
+			best = n
+		}
+	}
+	return best.value(), nil
+}
+
+func mathCompare(a, b mathNum) int {
+	if !a.isFloat && !b.isFloat {
+		switch {
+		case a.i < b.i:
+			return -1
+		case a.i > b.i:
+			return 1
+		}
+		return 0
+	}
+
+	switch {
+	case a.f < b.f:
+		return -1
+	case a.f > b.f:
+		return 1
+	}
+	return 0
+}
+
+func (mh mathHandlers) clamp(v, lo, hi any) (any, error) {
+	nums, err := mathNums([]any{v, lo, hi}, false)
+	if err != nil {
+		return nil, err
+	} else if mathCompare(nums[1], nums[2]) > 0 {
+		return nil, errors.New("LO is greater than HI")
+	}
+
+	switch {
+	case mathCompare(nums[0], nums[1]) < 0:
+		return nums[1].value(), nil
+	case mathCompare(nums[0], nums[2]) > 0:
+		return nums[2].value(), nil
+	}
+	return nums[0].value(), nil
+}
+
+// roundFunc returns a function which rounds a number to an int using fn.
+func (mh mathHandlers) roundFunc(fn func(float64) float64) func(any) (int, error) {
+	return func(v any) (int, error) {
+		n, err := mathNumOf(v)
+		if err != nil {
+			return 0, err
+		} else if !n.isFloat {
+			return n.i, nil
+		}
+		return floatToInt(fn(n.f))
+	}
+}
+
+func (mh mathHandlers) round(v any, places ...int) (any, error) {
+	if len(places) == 0 {
+		return mh.roundFunc(math.Round)(v)
+	}
+
+	n, err := mathNumOf(v)
+	if err != nil {
+		return nil, err
+	}
+	scale := math.Pow(10, float64(places[0]))
+	return math.Round(n.f*scale) / scale, nil
+}
+
+func (mh mathHandlers) pow(v, e any) (any, error) {
+	nums, err := mathNums([]any{v, e}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	base, exp := nums[0], nums[1]
+	if base.isFloat || exp.isFloat || exp.i < 0 {
+		return math.Pow(base.f, exp.f), nil
+	}
+
+	r := 1
+	for i := 0; i < exp.i; i++ {
+		if r, err = mulInt(r, base.i); err != nil {
+			return nil, err
+		} else if r == 0 || r == 1 {
+			break
+		}
+	}
+	if r == 1 && base.i == -1 && exp.i%2 == 1 {
+		r = -1
+	}
+	return r, nil
+}
+
+func (mh mathHandlers) log(n float64, base ...float64) float64 {
+	if len(base) == 0 {
+		return math.Log(n)
+	}
+	return math.Log(n) / math.Log(base[0])
+}
+
+func (mh mathHandlers) rand(ctx context.Context, args ucl.CallArgs) (any, error) {
+	var lo, hi int
+
+	switch args.NArgs() {
+	case 0:
+		return args.Rand().Float64(), nil
+	case 1:
+		if err := args.Bind(&hi); err != nil {
+			return nil, err
+		}
+	case 2:
+		if err := args.Bind(&lo, &hi); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("expected at most 2 args")
+	}
+
+	if hi <= lo {
+		return nil, errors.New("range is empty")
+	}
+
+	// As hi > lo, the size of the range is only negative if it overflows
+	n := hi - lo
+	if n < 0 {
+		return nil, errIntOverflow
+	}
+	return lo + args.Rand().Intn(n), nil
+}
+
+func addInt(a, b int) (int, error) {
+	s := a + b
+	if (b > 0 && s < a) || (b < 0 && s > a) {
+		return 0, errIntOverflow
+	}
+	return s, nil
+}
+
+func mulInt(a, b int) (int, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+
+	p := a * b
+	if p/b != a || (a == -1 && b == math.MinInt) || (b == -1 && a == math.MinInt) {
+		return 0, errIntOverflow
+	}
+	return p, nil
+}
+
+func floatToInt(f float64) (int, error) {
+	if math.IsNaN(f) || f < math.MinInt || f >= math.MaxInt {
+		return 0, fmt.Errorf("%v cannot be represented as an int", f)
+	}
+	return int(f), nil
+}
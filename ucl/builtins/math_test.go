@@ -0,0 +1,143 @@
+package builtins_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"ucl.lmika.dev/ucl"
+	"ucl.lmika.dev/ucl/builtins"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMath(t *testing.T) {
+	tests := []struct {
+		descr   string
+		eval    string
+		want    any
+		wantErr bool
+	}{
+		{descr: "add ints", eval: `math:add 1 2 3`, want: 6},
+		{descr: "add floats", eval: `math:add 1 2.5`, want: 3.5},
+		{descr: "add overflow", eval: `math:add (math:maxInt) 1`, wantErr: true},
+		{descr: "add negative overflow", eval: `math:add (math:minInt) -1`, wantErr: true},
+		{descr: "add not number", eval: `math:add 1 "2"`, wantErr: true},
+		{descr: "sub", eval: `math:sub 10 3 2`, want: 5},
+		{descr: "sub float", eval: `math:sub 1 0.5`, want: 0.5},
+		{descr: "sub overflow", eval: `math:sub (math:minInt) 1`, wantErr: true},
+		{descr: "sub minInt", eval: `math:sub 0 (math:minInt)`, wantErr: true},
+		{descr: "mul", eval: `math:mul 2 3 4`, want: 24},
+		{descr: "mul overflow", eval: `math:mul (math:maxInt) 2`, wantErr: true},
+		{descr: "mul minInt overflow", eval: `math:mul (math:minInt) -1`, wantErr: true},
+		{descr: "div", eval: `math:div 7 2`, want: 3.5},
+		{descr: "average", eval: `math:div (sum [1 2 3 4]) (len [1 2 3 4])`, want: 2.5},
+		{descr: "idiv", eval: `math:idiv 7 2`, want: 3},
+		{descr: "idiv negative", eval: `math:idiv -7 2`, want: -4},
+		{descr: "idiv zero", eval: `math:idiv 7 0`, wantErr: true},
+		{descr: "idiv overflow", eval: `math:idiv (math:minInt) -1`, wantErr: true},
+		{descr: "mod", eval: `math:mod 7 3`, want: 1},
+		{descr: "mod negative", eval: `math:mod -1 3`, want: 2},
+		{descr: "mod negative divisor", eval: `math:mod 7 -3`, want: -2},
+		{descr: "mod zero", eval: `math:mod 7 0`, wantErr: true},
+
+		{descr: "abs int", eval: `math:abs -3`, want: 3},
+		{descr: "abs float", eval: `math:abs -2.5`, want: 2.5},
+		{descr: "abs overflow", eval: `math:abs (math:minInt)`, wantErr: true},
+		{descr: "min", eval: `math:min 3 1 2`, want: 1},
+		{descr: "min mixed", eval: `math:min 3 1.5 2`, want: 1.5},
+		{descr: "max list", eval: `math:max [3 7] 5`, want: 7},
+		{descr: "max empty", eval: `math:max []`, wantErr: true},
+		{descr: "clamp low", eval: `math:clamp -5 0 10`, want: 0},
+		{descr: "clamp high", eval: `math:clamp 15 0 10`, want: 10},
+		{descr: "clamp within", eval: `math:clamp 2.5 0 10`, want: 2.5},
+		{descr: "clamp bad range", eval: `math:clamp 5 10 0`, wantErr: true},
+
+		{descr: "floor", eval: `math:floor -2.5`, want: -3},
+		{descr: "ceil", eval: `math:ceil 2.1`, want: 3},
+		{descr: "trunc", eval: `math:trunc -2.7`, want: -2},
+		{descr: "round", eval: `math:round 2.5`, want: 3},
+		{descr: "round int", eval: `math:round 7`, want: 7},
+		{descr: "round places", eval: `math:round 3.14159 2`, want: 3.14},
+		{descr: "floor inf", eval: `math:floor (math:inf)`, wantErr: true},
+		{descr: "percentage", eval: `math:div 37 120 | math:mul 100 | math:round 1`, want: 30.8},
+
+		{descr: "pow", eval: `math:pow 2 10`, want: 1024},
+		{descr: "pow zero", eval: `math:pow 5 0`, want: 1},
+		{descr: "pow negative base", eval: `math:pow -1 3`, want: -1},
+		{descr: "pow negative exp", eval: `math:pow 2 -1`, want: 0.5},
+		{descr: "pow float", eval: `math:pow 4 0.5`, want: 2.0},
+		{descr: "pow overflow", eval: `math:pow 2 63`, wantErr: true},
+		{descr: "sqrt", eval: `math:sqrt 16`, want: 4.0},
+		{descr: "exp", eval: `math:exp 0`, want: 1.0},
+		{descr: "log", eval: `math:log (math:e)`, want: 1.0},
+		{descr: "log base", eval: `math:log 8 2`, want: 3.0},
+		{descr: "log10", eval: `math:log10 1000`, want: 3.0},
+		{descr: "log2", eval: `math:log2 8`, want: 3.0},
+		{descr: "sin", eval: `math:sin 0`, want: 0.0},
+		{descr: "cos", eval: `math:cos (math:pi)`, want: -1.0},
+		{descr: "atan2", eval: `math:atan2 1 1 | math:mul 4`, want: math.Pi},
+
+		{descr: "pi", eval: `math:pi`, want: math.Pi},
+		{descr: "maxInt", eval: `math:maxInt`, want: math.MaxInt},
+		{descr: "minInt", eval: `math:minInt`, want: math.MinInt},
+		{descr: "constant with args", eval: `math:pi 1`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			inst := ucl.New(ucl.WithModule(builtins.Math()))
+			res, err := inst.Eval(context.Background(), tt.eval)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				if f, ok := tt.want.(float64); ok {
+					assert.InDelta(t, f, res, 1e-9)
+				} else {
+					assert.Equal(t, tt.want, res)
+				}
+			}
+		})
+	}
+}
+
+func TestMath_Rand(t *testing.T) {
+	evalWithSeed := func(t *testing.T, seed int64, expr string) any {
+		inst := ucl.New(ucl.WithModule(builtins.Math()), ucl.WithRandSeed(seed))
+		res, err := inst.Eval(context.Background(), expr)
+		assert.NoError(t, err)
+		return res
+	}
+
+	t.Run("same seed gives same numbers", func(t *testing.T) {
+		expr := `[(math:rand) (math:rand 100) (math:rand 10 20)]`
+		assert.Equal(t, evalWithSeed(t, 42, expr), evalWithSeed(t, 42, expr))
+		assert.NotEqual(t, evalWithSeed(t, 42, expr), evalWithSeed(t, 43, expr))
+	})
+
+	t.Run("ranges", func(t *testing.T) {
+		res := evalWithSeed(t, 1, `map (range 100) { |i| [(math:rand) (math:rand 5) (math:rand -3 3)] }`)
+		for _, r := range res.([]any) {
+			rs := r.([]any)
+			assert.GreaterOrEqual(t, rs[0], 0.0)
+			assert.Less(t, rs[0], 1.0)
+			assert.GreaterOrEqual(t, rs[1], 0)
+			assert.Less(t, rs[1], 5)
+			assert.GreaterOrEqual(t, rs[2], -3)
+			assert.Less(t, rs[2], 3)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, expr := range []string{
+			`math:rand 0`, `math:rand 5 5`, `math:rand 1 2 3`, `math:rand "x"`,
+			`math:rand -5 (math:maxInt)`, `math:rand (math:minInt) (math:maxInt)`,
+		} {
+			inst := ucl.New(ucl.WithModule(builtins.Math()))
+			_, err := inst.Eval(context.Background(), expr)
+			assert.Error(t, err, expr)
+		}
+	})
+}
@@ -1,21 +1,59 @@
 package builtins
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
 	"ucl.lmika.dev/ucl"
 )
 
+// osExecWaitDelay is how long to wait for the output of a program to close once it has
+// exited or been killed.
+const osExecWaitDelay = time.Second
+
 type osHandlers struct {
 }
 
 func OS() ucl.Module {
 	osh := osHandlers{}
 
+	execSwitches := []ucl.SwitchDoc{
+		{Name: "env", Arg: "HASH", Description: "Environment variables to set, in addition to those of this process"},
+		{Name: "dir", Arg: "DIR", Description: "Working directory of the program"},
+		{Name: "stdin", Arg: "INPUT", Description: "Input of the program, as a string, or a list or iterator of lines"},
+		{Name: "lines", Description: "Return an iterator of the lines of stdout as they are written"},
+		{Name: "check", Description: "Return an error if the program exits with a non-zero code"},
+	}
+
 	return ucl.Module{
 		Name: "os",
 		Builtins: map[string]ucl.BuiltinHandler{
-			"env": osh.env,
+			"env":  osh.env,
+			"exec": ucl.Func(osh.exec),
+			"sh":   ucl.Func(osh.sh),
+		},
+		Docs: map[string]ucl.Doc{
+			"env": {Description: "Returns the value of an environment variable, or DEFAULT if not set.", Args: []string{"NAME", "[DEFAULT]"}},
+			"exec": {
+				Description: "Runs the program CMD with the arguments, and returns a hash of the stdout, stderr and\nexit code of the program once it exits. The program is killed if evaluation is cancelled.\nWith -lines, the lines of stdout are returned as an iterator instead, with a non-zero\nexit code returned as an error once the lines have been read. The program is started when\nthe first line is read, and killed if the iterator is closed before then, such as by take.",
+				Args:        []string{"CMD", "ARGS..."},
+				Switches:    execSwitches,
+			},
+			"sh": {
+				Description: "Runs SCRIPT using the shell, with the arguments available as $1, $2, etc. This otherwise\nbehaves like exec.",
+				Args:        []string{"SCRIPT", "ARGS..."},
+				Switches:    execSwitches,
+			},
 		},
 	}
 }
@@ -38,3 +76,259 @@ func (oh osHandlers) env(ctx context.Context, args ucl.CallArgs) (any, error) {
 
 	return "", nil
 }
+
+type osExecSwitches struct {
+	Env   map[string]any `ucl:"-env"`
+	Dir   *string        `ucl:"-dir"`
+	Stdin any            `ucl:"-stdin"`
+	Lines bool           `ucl:"-lines"`
+	Check bool           `ucl:"-check"`
+}
+
+func (oh osHandlers) exec(ctx context.Context, name string, sw osExecSwitches, args ...string) (any, error) {
+	return oh.run(ctx, exec.CommandContext(ctx, name, args...), sw)
+}
+
+func (oh osHandlers) sh(ctx context.Context, script string, sw osExecSwitches, args ...string) (any, error) {
+	if runtime.GOOS == "windows" {
+		return oh.run(ctx, exec.CommandContext(ctx, "cmd", append([]string{"/C", script}, args...)...), sw)
+	}
+	return oh.run(ctx, exec.CommandContext(ctx, "/bin/sh", append([]string{"-c", script, "sh"}, args...)...), sw)
+}
+
+func (oh osHandlers) run(ctx context.Context, cmd *exec.Cmd, sw osExecSwitches) (any, error) {
+	cmd.WaitDelay = osExecWaitDelay
+	if sw.Dir != nil {
+		cmd.Dir = *sw.Dir
+	}
+	if len(sw.Env) > 0 {
+		keys := make([]string, 0, len(sw.Env))
+		for k := range sw.Env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		cmd.Env = os.Environ()
+		for _, k := range keys {
+			cmd.Env = append(cmd.Env, k+"="+fmt.Sprint(sw.Env[k]))
+		}
+	}
+
+	var stdinIt ucl.Iterator
+	switch in := sw.Stdin.(type) {
+	case nil:
+	case string:
+		cmd.Stdin = strings.NewReader(in)
+	case []any:
+		cmd.Stdin = strings.NewReader(joinLines(in))
+	case ucl.Iterator:
+		stdinIt = in
+		cmd.Stdin = &osIterReader{ctx: ctx, it: in}
+	default:
+		cmd.Stdin = strings.NewReader(fmt.Sprint(in))
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if sw.Lines {
+		return oh.runLines(cmd, &stderr, stdinIt), nil
+	}
+	defer stdinIt.Close()
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	code := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		} else if !errors.As(err, &exitErr) {
+			return nil, err
+		} else if sw.Check {
+			return nil, osExitError(cmd, err, &stderr)
+		}
+		code = exitErr.ExitCode()
+	}
+
+	res := ucl.NewOrderedHash()
+	res.Set("stdout", stdout.String())
+	res.Set("stderr", stderr.String())
+	res.Set("code", code)
+	return res, nil
+}
+
+// runLines returns an iterator of the lines of the stdout of cmd. The program is started when
+// the first line is read, so an iterator which is never read does not leave it running. It is
+// waited on once stdout is closed, or killed if the iterator is closed before then. The
+// iterator of stdin, if any, is closed once the program has exited.
+func (oh osHandlers) runLines(cmd *exec.Cmd, stderr *bytes.Buffer, stdin ucl.Iterator) ucl.Iterator {
+	var (
+		scanner       *bufio.Scanner
+		started, done bool
+	)
+
+	start := func() error {
+		started = true
+
+		stdout, err := cmd.StdoutPipe()
+		if err == nil {
+			err = cmd.Start()
+		}
+		if err != nil {
+			done = true
+			stdin.Close()
+			return err
+		}
+
+		scanner = bufio.NewScanner(stdout)
+		return nil
+	}
+	wait := func() error {
+		done = true
+		defer stdin.Close()
+		return cmd.Wait()
+	}
+	kill := func() {
+		if done {
+			return
+		} else if !started {
+			done = true
+			stdin.Close()
+			return
+		}
+		_ = cmd.Process.Kill()
+		_ = wait()
+	}
+
+	return ucl.NewIteratorWithClose(func(ctx context.Context) (any, bool, error) {
+		if done {
+			return nil, false, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			kill()
+			return nil, false, err
+		}
+
+		if !started {
+			if err := start(); err != nil {
+				return nil, false, err
+			}
+		}
+
+		if scanner.Scan() {
+			return scanner.Text(), true, nil
+		}
+
+		if err := scanner.Err(); err != nil {
+			kill()
+			return nil, false, err
+		}
+		if err := wait(); err != nil {
+			return nil, false, osExitError(cmd, err, stderr)
+		}
+		return nil, false, nil
+	}, kill)
+}
+
+func osExitError(cmd *exec.Cmd, err error, stderr *bytes.Buffer) error {
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return fmt.Errorf("%v: %w: %v", cmd.Args[0], err, msg)
+	}
+	return fmt.Errorf("%v: %w", cmd.Args[0], err)
+}
+
+// osIterReader reads the values of an iterator as lines. Values are only taken from the
+// iterator as they are read.
+type osIterReader struct {
+	ctx context.Context
+	it  ucl.Iterator
+	buf []byte
+}
+
+func (r *osIterReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		v, ok, err := r.it.Next(r.ctx)
+		if err != nil {
+			return 0, err
+		} else if !ok {
+			return 0, io.EOF
+		}
+		r.buf = []byte(joinLines([]any{v}))
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// ExecMissingBuiltinHandler returns a MissingBuiltinHandler which runs unknown commands as
+// programs found on the PATH, allowing an instance to be used like a shell. The positional
+// arguments are passed to the program first, with lists expanded into separate arguments,
+// followed by the switches in the order they were given, each followed by its arguments. A
+// switch given more than once is passed each time:
+//
+//	ls -la "/tmp"             # runs: ls -la /tmp
+//	git log -n 5 --oneline    # runs: git log -n 5 --oneline
+//	grep -e "a" -e "b" "f"    # runs: grep -e a -e b f
+//
+// Stdout is written to the output of the instance, while stdin and stderr are those of this
+// process. The command returns nil, or an error if the program exits with a non-zero code.
+// Use os:exec to capture the output of a program.
+func ExecMissingBuiltinHandler() ucl.MissingBuiltinHandler {
+	return func(ctx context.Context, name string, args ucl.CallArgs) (any, error) {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			return nil, errors.New("unknown command: " + name)
+		}
+
+		var argv []string
+		for args.NArgs() > 0 {
+			var v any
+			if err := args.Bind(&v); err != nil {
+				return nil, err
+			}
+			argv = appendArgv(argv, v)
+		}
+		for _, sw := range args.OrderedSwitches() {
+			argv = append(argv, "-"+sw.Name)
+			for _, v := range sw.Args {
+				argv = appendArgv(argv, v)
+			}
+		}
+
+		cmd := exec.CommandContext(ctx, path, argv...)
+		cmd.Args[0] = name
+		cmd.WaitDelay = osExecWaitDelay
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = args.Out()
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("%v: %w", name, err)
+		}
+		return nil, nil
+	}
+}
+
+// appendArgv adds the value to the arguments of a program. Lists are added as separate
+// arguments, and nil values are skipped.
+func appendArgv(argv []string, v any) []string {
+	switch t := v.(type) {
+	case nil:
+		return argv
+	case string:
+		return append(argv, t)
+	case []any:
+		for _, e := range t {
+			argv = appendArgv(argv, e)
+		}
+		return argv
+	}
+	return append(argv, fmt.Sprint(v))
+}
@@ -1,11 +1,21 @@
 package builtins_test
 
 import (
+	"bytes"
 	"context"
-	"github.com/stretchr/testify/assert"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
 	"testing"
+	"time"
+
 	"ucl.lmika.dev/ucl"
 	"ucl.lmika.dev/ucl/builtins"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestOS_Env(t *testing.T) {
@@ -34,3 +44,154 @@ func TestOS_Env(t *testing.T) {
 		})
 	}
 }
+
+func TestOS_Exec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		descr   string
+		eval    string
+		want    any
+		wantErr bool
+	}{
+		{descr: "exec", eval: `os:exec "echo" "hello" 123`, want: map[string]any{"stdout": "hello 123\n", "stderr": "", "code": 0}},
+		{descr: "exec stdout", eval: `os:exec "echo" "hello" | index "stdout"`, want: "hello\n"},
+		{descr: "exec exit code", eval: `os:exec "false" | index "code"`, want: 1},
+		{descr: "exec check", eval: `os:exec "false" -check`, wantErr: true},
+		{descr: "exec missing program", eval: `os:exec "no-such-program-ucl"`, wantErr: true},
+		{descr: "exec stdin string", eval: `os:exec "cat" -stdin "from stdin" | index "stdout"`, want: "from stdin"},
+		{descr: "exec stdin list", eval: `os:exec "sort" -stdin ["b" "c" "a"] | index "stdout"`, want: "a\nb\nc\n"},
+		{descr: "exec stdin iterator", eval: `os:exec "cat" -stdin (os:exec "printf" "x\ny\n" -lines | map { |l| toUpper $l }) | index "stdout"`, want: "X\nY\n"},
+		{descr: "exec env", eval: `os:exec "printenv" "UCL_TEST_VAR" -env [UCL_TEST_VAR:"set" OTHER:1] | index "stdout"`, want: "set\n"},
+		{descr: "exec dir", eval: fmt.Sprintf(`os:exec "pwd" -dir %q | index "stdout"`, dir), want: dir + "\n"},
+
+		{descr: "exec lines", eval: `os:exec "printf" "one\ntwo\nthree\n" -lines | map { |l| $l }`, want: []any{"one", "two", "three"}},
+		{descr: "exec lines take", eval: `os:exec "seq" 1 5 -lines | take 2`, want: []any{"1", "2"}},
+		{descr: "exec lines error", eval: `os:sh "echo partial ; echo failed >&2 ; exit 2" -lines | map { |l| $l }`, wantErr: true},
+		{descr: "exec lines missing program", eval: `os:exec "no-such-program-ucl" -lines | map { |l| $l }`, wantErr: true},
+
+		{descr: "sh", eval: `os:sh "echo $1-$2 | tr a-z A-Z" "ab" "cd" | index "stdout"`, want: "AB-CD\n"},
+		{descr: "sh stderr", eval: `os:sh "echo oops >&2 ; exit 3"`, want: map[string]any{"stdout": "", "stderr": "oops\n", "code": 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			inst := ucl.New(ucl.WithModule(builtins.OS()))
+			res, err := inst.Eval(context.Background(), tt.eval)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+		})
+	}
+
+	t.Run("lines error includes stderr", func(t *testing.T) {
+		inst := ucl.New(ucl.WithModule(builtins.OS()))
+		_, err := inst.Eval(context.Background(), `os:sh "echo failed >&2 ; exit 2" -lines | map { |l| $l }`)
+		assert.ErrorContains(t, err, "failed")
+	})
+
+	t.Run("partially consumed lines kills program", func(t *testing.T) {
+		for _, expr := range []string{
+			`os:sh "echo $$ ; exec yes" -lines | take 2`,
+			`os:sh "echo $$ ; exec yes" -lines | foreach { |l| break [$l] }`,
+		} {
+			inst := ucl.New(ucl.WithModule(builtins.OS()))
+			res, err := inst.Eval(context.Background(), expr)
+			assert.NoError(t, err, expr)
+
+			pid, err := strconv.Atoi(res.([]any)[0].(string))
+			assert.NoError(t, err, expr)
+
+			// The program has been waited on, so signalling it fails
+			p, err := os.FindProcess(pid)
+			if err == nil {
+				err = p.Signal(syscall.Signal(0))
+			}
+			assert.Error(t, err, expr)
+		}
+	})
+
+	t.Run("lines starts program when read", func(t *testing.T) {
+		marker := filepath.Join(t.TempDir(), "started")
+
+		inst := ucl.New(ucl.WithModule(builtins.OS()))
+		_, err := inst.Eval(context.Background(), fmt.Sprintf(`set it (os:sh "touch $1 ; echo done" %q -lines) ; ()`, marker))
+		assert.NoError(t, err)
+		assert.Never(t, func() bool {
+			_, err := os.Stat(marker)
+			return err == nil
+		}, 200*time.Millisecond, 10*time.Millisecond)
+
+		res, err := inst.Eval(context.Background(), `$it | map { |l| $l }`)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"done"}, res)
+		assert.FileExists(t, marker)
+	})
+
+	t.Run("cancel kills program", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		inst := ucl.New(ucl.WithModule(builtins.OS()))
+		_, err := inst.Eval(ctx, `os:exec "sleep" 10`)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+}
+
+func TestExecMissingBuiltinHandler(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires POSIX programs")
+	}
+
+	tests := []struct {
+		descr   string
+		eval    string
+		want    string
+		wantErr bool
+	}{
+		{descr: "args", eval: `printf "%s|" "a" 1 2.5`, want: "a|1|2.5|"},
+		{descr: "lists expanded", eval: `printf "%s|" ["a" "b"] "c"`, want: "a|b|c|"},
+		{descr: "switches", eval: `printf "%s|" "a" -z "b" --long -n 5`, want: "a|-z|b|--long|-n|5|"},
+		{descr: "repeated switches", eval: `printf "[%s]" "a" -e "foo" -e "bar" "file"`, want: "[a][-e][foo][-e][bar][file]"},
+		{descr: "repeated flags", eval: `printf "[%s]" -v -v "x"`, want: "[-v][-v][x]"},
+		{descr: "nil skipped", eval: `printf "%s|" () "a"`, want: "a|"},
+		{descr: "within procs", eval: `proc greet { |n| printf "hello %s\n" $n } ; greet "world"`, want: "hello world\n"},
+		{descr: "builtins take precedence", eval: `echo "from builtin"`, want: "from builtin\n"},
+		{descr: "exit code", eval: `sh -c "exit 3"`, wantErr: true},
+		{descr: "unknown program", eval: `no-such-program-ucl "x"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			outW := bytes.NewBuffer(nil)
+			inst := ucl.New(
+				ucl.WithOut(outW),
+				ucl.WithMissingBuiltinHandler(builtins.ExecMissingBuiltinHandler()),
+			)
+
+			res, err := inst.Eval(context.Background(), tt.eval)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Nil(t, res)
+				assert.Equal(t, tt.want, outW.String())
+			}
+		})
+	}
+
+	t.Run("unknown program error", func(t *testing.T) {
+		inst := ucl.New(ucl.WithMissingBuiltinHandler(builtins.ExecMissingBuiltinHandler()))
+		_, err := inst.Eval(context.Background(), `no-such-program-ucl`)
+		assert.EqualError(t, err, "unknown command: no-such-program-ucl")
+	})
+}
@@ -0,0 +1,231 @@
+package builtins
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"ucl.lmika.dev/ucl"
+)
+
+// reCacheSize is the maximum number of compiled patterns held by the cache before it is
+// cleared.
+const reCacheSize = 256
+
+type reHandlers struct {
+}
+
+// reCache holds the compiled patterns of an instance.
+type reCache struct {
+	mu  sync.Mutex
+	res map[string]*regexp.Regexp
+}
+
+type reCacheKey struct{}
+
+// RE returns a module of regular expression functions, using the syntax of Go's regexp
+// package. As with the strs module, the string to match is the first argument:
+//
+//	filter $lines { |l| re:match $l "ERROR|WARN" }
+//
+// Compiled patterns are cached by each instance the module is added to.
+func RE() ucl.Module {
+	rh := reHandlers{}
+
+	return ucl.Module{
+		Name: "re",
+		Builtins: map[string]ucl.BuiltinHandler{
+			"match":   withReCache(ucl.Func(rh.match)),
+			"find":    withReCache(ucl.Func(rh.find)),
+			"findAll": withReCache(ucl.Func(rh.findAll)),
+			"replace": withReCache(rh.replace),
+			"split":   withReCache(ucl.Func(rh.split)),
+		},
+		Docs: map[string]ucl.Doc{
+			"match": {Description: "Returns true if STR contains a match of PATTERN.", Args: []string{"STR", "PATTERN"}},
+			"find": {
+				Description: "Returns the first match of PATTERN in STR, or nil if there is no match.\nIf PATTERN has named groups, the match is returned as a hash of the group names to the\nmatched text. Otherwise the matched text is returned.",
+				Args:        []string{"STR", "PATTERN"},
+			},
+			"findAll": {
+				Description: "Returns a list of the matches of PATTERN in STR, in the same form as find.",
+				Args:        []string{"STR", "PATTERN"},
+				Switches:    []ucl.SwitchDoc{{Name: "n", Arg: "N", Description: "Return at most N matches"}},
+			},
+			"replace": {
+				Description: "Replaces each match of PATTERN in STR with REPL. If REPL is a string, \"$1\" or \"${name}\"\nare expanded to the text of the group. If REPL is a block, it is invoked with the matched\ntext followed by the text of each group, and the match is replaced with the result.",
+				Args:        []string{"STR", "PATTERN", "REPL"},
+			},
+			"split": {
+				Description: "Splits STR around each match of PATTERN.",
+				Args:        []string{"STR", "PATTERN"},
+				Switches:    []ucl.SwitchDoc{{Name: "n", Arg: "N", Description: "Return at most N substrings, with the last being the unsplit remainder"}},
+			},
+		},
+	}
+}
+
+type reCountSwitch struct {
+	N *int `ucl:"-n"`
+}
+
+func (rh reHandlers) match(ctx context.Context, s, pattern string) (bool, error) {
+	re, err := reCompile(ctx, pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(s), nil
+}
+
+func (rh reHandlers) find(ctx context.Context, s, pattern string) (any, error) {
+	re, err := reCompile(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	m := re.FindStringSubmatch(s)
+	if m == nil {
+		return nil, nil
+	}
+	return reMatchValue(re, m), nil
+}
+
+func (rh reHandlers) findAll(ctx context.Context, s, pattern string, sw reCountSwitch) ([]any, error) {
+	re, err := reCompile(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	n := -1
+	if sw.N != nil {
+		n = *sw.N
+	}
+
+	ms := re.FindAllStringSubmatch(s, n)
+	res := make([]any, len(ms))
+	for i, m := range ms {
+		res[i] = reMatchValue(re, m)
+	}
+	return res, nil
+}
+
+func (rh reHandlers) replace(ctx context.Context, args ucl.CallArgs) (any, error) {
+	var s, pattern string
+	if err := args.Bind(&s, &pattern); err != nil {
+		return nil, err
+	}
+
+	re, err := reCompile(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var inv ucl.Invokable
+	if !args.CanBind(&inv) {
+		var repl string
+		if err := args.Bind(&repl); err != nil {
+			return nil, err
+		}
+		return re.ReplaceAllString(s, repl), nil
+	} else if err := args.Bind(&inv); err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringSubmatchIndex(s, -1) {
+		invArgs := make([]any, len(loc)/2)
+		for i := range invArgs {
+			if loc[2*i] >= 0 {
+				invArgs[i] = s[loc[2*i]:loc[2*i+1]]
+			} else {
+				invArgs[i] = ""
+			}
+		}
+
+		r, err := inv.Invoke(ctx, invArgs...)
+		if err != nil {
+			return nil, err
+		}
+
+		sb.WriteString(s[last:loc[0]])
+		if r != nil {
+			sb.WriteString(fmt.Sprint(r))
+		}
+		last = loc[1]
+	}
+	sb.WriteString(s[last:])
+	return sb.String(), nil
+}
+
+func (rh reHandlers) split(ctx context.Context, s, pattern string, sw reCountSwitch) ([]any, error) {
+	re, err := reCompile(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	n := -1
+	if sw.N != nil {
+		n = *sw.N
+	}
+	return strsList(re.Split(s, n)), nil
+}
+
+// withReCache returns a handler which calls h with the pattern cache of the instance added to
+// the context.
+func withReCache(h ucl.BuiltinHandler) ucl.BuiltinHandler {
+	return func(ctx context.Context, args ucl.CallArgs) (any, error) {
+		rc := args.InstState(reCacheKey{}, func() any {
+			return &reCache{res: make(map[string]*regexp.Regexp)}
+		})
+		return h(context.WithValue(ctx, reCacheKey{}, rc), args)
+	}
+}
+
+// reCompile compiles pattern, using the pattern cache of the instance if there is one.
+func reCompile(ctx context.Context, pattern string) (*regexp.Regexp, error) {
+	rc, ok := ctx.Value(reCacheKey{}).(*reCache)
+	if !ok {
+		return regexp.Compile(pattern)
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if re, ok := rc.res[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rc.res) >= reCacheSize {
+		clear(rc.res)
+	}
+	rc.res[pattern] = re
+	return re, nil
+}
+
+// reMatchValue returns a hash of the named groups of a match, or the matched text if the
+// pattern has no named groups.
+func reMatchValue(re *regexp.Regexp, m []string) any {
+	var groups map[string]any
+	for i, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		if groups == nil {
+			groups = make(map[string]any)
+		}
+		groups[name] = m[i]
+	}
+
+	if groups == nil {
+		return m[0]
+	}
+	return groups
+}
@@ -0,0 +1,63 @@
+package builtins_test
+
+import (
+	"context"
+	"testing"
+
+	"ucl.lmika.dev/ucl"
+	"ucl.lmika.dev/ucl/builtins"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRe(t *testing.T) {
+	tests := []struct {
+		descr   string
+		eval    string
+		want    any
+		wantErr bool
+	}{
+		{descr: "match", eval: `re:match "request id=123" "id=\\d+"`, want: true},
+		{descr: "no match", eval: `re:match "request" "id=\\d+"`, want: false},
+		{descr: "match in pipe", eval: `"ERROR: bad" | re:match "^ERROR"`, want: true},
+		{descr: "match as filter predicate", eval: `filter ["INFO a" "ERROR b" "WARN c" "ERROR d"] { |l| re:match $l "^(ERROR|WARN)" }`, want: []any{"ERROR b", "WARN c", "ERROR d"}},
+		{descr: "bad pattern", eval: `re:match "abc" "("`, wantErr: true},
+
+		{descr: "find text", eval: `re:find "order 42 item 7" "\\d+"`, want: "42"},
+		{descr: "find named groups", eval: `re:find "user=alice id=42" "user=(?P<user>\\w+) id=(?P<id>\\d+)"`, want: map[string]any{"user": "alice", "id": "42"}},
+		{descr: "find named group value", eval: `(re:find "id=42" "id=(?P<id>\\d+)").id`, want: "42"},
+		{descr: "find no match", eval: `re:find "nothing" "\\d+"`, want: nil},
+
+		{descr: "findAll text", eval: `re:findAll "a1 b22 c333" "\\d+"`, want: []any{"1", "22", "333"}},
+		{descr: "findAll limit", eval: `re:findAll "a1 b22 c333" "\\d+" -n 2`, want: []any{"1", "22"}},
+		{descr: "findAll named groups", eval: `re:findAll "a=1 b=2" "(?P<k>\\w)=(?P<v>\\d)"`, want: []any{
+			map[string]any{"k": "a", "v": "1"},
+			map[string]any{"k": "b", "v": "2"},
+		}},
+		{descr: "findAll no match", eval: `re:findAll "abc" "\\d+"`, want: []any{}},
+
+		{descr: "replace string", eval: `re:replace "a1 b22" "\\d+" "#"`, want: "a# b#"},
+		{descr: "replace expand", eval: `re:replace "key=value" "(\\w+)=(\\w+)" "$2=$1"`, want: "value=key"},
+		{descr: "replace block", eval: `re:replace "a1 b22" "\\d+" { |m| add $m 1 }`, want: "a2 b23"},
+		{descr: "replace block with groups", eval: `re:replace "x=1 y=2" "(\\w)=(\\d)" { |m k v| cat $v "=" $k }`, want: "1=x 2=y"},
+		{descr: "replace anchored", eval: `re:replace "aaa" "^a" { |m| "b" }`, want: "baa"},
+		{descr: "replace in pipe", eval: `"a-b" | re:replace "-" "+"`, want: "a+b"},
+		{descr: "replace block error", eval: `re:replace "a1" "\\d" { |m| error "bad" }`, wantErr: true},
+
+		{descr: "split", eval: `re:split "a, b,c ,  d" "\\s*,\\s*"`, want: []any{"a", "b", "c", "d"}},
+		{descr: "split limit", eval: `re:split "a1b2c" "\\d" -n 2`, want: []any{"a", "b2c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			inst := ucl.New(ucl.WithModule(builtins.RE()))
+			res, err := inst.Eval(context.Background(), tt.eval)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package builtins
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ucl.lmika.dev/ucl"
+)
+
+type strsHandlers struct {
+}
+
+// Strs returns a module of string functions. The string operated on is always the first
+// argument, so that the functions can be used after a pipe:
+//
+//	$line | strs:trim | strs:split ","
+//
+// Positions and lengths are in runes rather than bytes.
+func Strs() ucl.Module {
+	sh := strsHandlers{}
+
+	return ucl.Module{
+		Name: "strs",
+		Builtins: map[string]ucl.BuiltinHandler{
+			"split":      ucl.Func(sh.split),
+			"join":       ucl.Func(sh.join),
+			"fields":     ucl.Func(sh.fields),
+			"trim":       ucl.Func(sh.trim),
+			"trimLeft":   ucl.Func(sh.trimLeft),
+			"trimRight":  ucl.Func(sh.trimRight),
+			"trimPrefix": ucl.Func(strings.TrimPrefix),
+			"trimSuffix": ucl.Func(strings.TrimSuffix),
+			"replace":    ucl.Func(sh.replace),
+			"contains":   ucl.Func(strings.Contains),
+			"hasPrefix":  ucl.Func(strings.HasPrefix),
+			"hasSuffix":  ucl.Func(strings.HasSuffix),
+			"index":      ucl.Func(sh.index),
+			"substr":     ucl.Func(sh.substr),
+			"toLower":    ucl.Func(strings.ToLower),
+			"toUpper":    ucl.Func(strings.ToUpper),
+			"title":      ucl.Func(sh.title),
+			"repeat":     ucl.Func(sh.repeat),
+			"padLeft":    ucl.Func(sh.padLeft),
+			"padRight":   ucl.Func(sh.padRight),
+			"format":     ucl.Func(sh.format),
+		},
+		Docs: map[string]ucl.Doc{
+			"split": {
+				Description: "Splits STR around each instance of SEP. An empty SEP splits STR into runes.",
+				Args:        []string{"STR", "SEP"},
+				Switches:    []ucl.SwitchDoc{{Name: "n", Arg: "N", Description: "Return at most N substrings, with the last being the unsplit remainder"}},
+			},
+			"join":       {Description: "Joins the elements of LIST, separated by SEP.", Args: []string{"LIST", "[SEP]"}},
+			"fields":     {Description: "Splits STR around runs of whitespace.", Args: []string{"STR"}},
+			"trim":       {Description: "Removes leading and trailing whitespace, or runes in CUTSET, from STR.", Args: []string{"STR", "[CUTSET]"}},
+			"trimLeft":   {Description: "Removes leading whitespace, or runes in CUTSET, from STR.", Args: []string{"STR", "[CUTSET]"}},
+			"trimRight":  {Description: "Removes trailing whitespace, or runes in CUTSET, from STR.", Args: []string{"STR", "[CUTSET]"}},
+			"trimPrefix": {Description: "Removes PREFIX from the start of STR, if present.", Args: []string{"STR", "PREFIX"}},
+			"trimSuffix": {Description: "Removes SUFFIX from the end of STR, if present.", Args: []string{"STR", "SUFFIX"}},
+			"replace": {
+				Description: "Replaces each instance of OLD in STR with NEW.",
+				Args:        []string{"STR", "OLD", "NEW"},
+				Switches:    []ucl.SwitchDoc{{Name: "n", Arg: "N", Description: "Replace at most the first N instances"}},
+			},
+			"contains":  {Description: "Returns true if STR contains SUBSTR.", Args: []string{"STR", "SUBSTR"}},
+			"hasPrefix": {Description: "Returns true if STR starts with PREFIX.", Args: []string{"STR", "PREFIX"}},
+			"hasSuffix": {Description: "Returns true if STR ends with SUFFIX.", Args: []string{"STR", "SUFFIX"}},
+			"index":     {Description: "Returns the rune position of the first instance of SUBSTR in STR, or -1 if not present.", Args: []string{"STR", "SUBSTR"}},
+			"substr": {
+				Description: "Returns the runes of STR from START up to, but not including, END.\nNegative positions are relative to the end of STR. END defaults to the end of STR.",
+				Args:        []string{"STR", "START", "[END]"},
+			},
+			"toLower":  {Description: "Returns STR converted to lower case.", Args: []string{"STR"}},
+			"toUpper":  {Description: "Returns STR converted to upper case.", Args: []string{"STR"}},
+			"title":    {Description: "Returns STR with the first letter of each word converted to upper case.", Args: []string{"STR"}},
+			"repeat":   {Description: "Returns STR repeated COUNT times.", Args: []string{"STR", "COUNT"}},
+			"padLeft":  {Description: "Pads the start of STR with PAD, which defaults to a space, until it is WIDTH runes long.", Args: []string{"STR", "WIDTH", "[PAD]"}},
+			"padRight": {Description: "Pads the end of STR with PAD, which defaults to a space, until it is WIDTH runes long.", Args: []string{"STR", "WIDTH", "[PAD]"}},
+			"format": {
+				Description: "Formats ARGS according to FORMAT, using the verbs of Go's fmt package, such as \"%-10s %5d\".",
+				Args:        []string{"FORMAT", "ARGS..."},
+			},
+		},
+	}
+}
+
+type strsCountSwitch struct {
+	N *int `ucl:"-n"`
+}
+
+func (sh strsHandlers) split(s, sep string, sw strsCountSwitch) []any {
+	n := -1
+	if sw.N != nil {
+		n = *sw.N
+	}
+	return strsList(strings.SplitN(s, sep, n))
+}
+
+func (sh strsHandlers) join(items []any, sep ...string) string {
+	strs := make([]string, len(items))
+	for i, item := range items {
+		if item != nil {
+			strs[i] = fmt.Sprint(item)
+		}
+	}
+	return strings.Join(strs, strings.Join(sep, ""))
+}
+
+func (sh strsHandlers) fields(s string) []any {
+	return strsList(strings.Fields(s))
+}
+
+func (sh strsHandlers) trim(s string, cutset ...string) string {
+	if len(cutset) == 0 {
+		return strings.TrimSpace(s)
+	}
+	return strings.Trim(s, strings.Join(cutset, ""))
+}
+
+func (sh strsHandlers) trimLeft(s string, cutset ...string) string {
+	if len(cutset) == 0 {
+		return strings.TrimLeftFunc(s, unicode.IsSpace)
+	}
+	return strings.TrimLeft(s, strings.Join(cutset, ""))
+}
+
+func (sh strsHandlers) trimRight(s string, cutset ...string) string {
+	if len(cutset) == 0 {
+		return strings.TrimRightFunc(s, unicode.IsSpace)
+	}
+	return strings.TrimRight(s, strings.Join(cutset, ""))
+}
+
+func (sh strsHandlers) replace(s, old, new string, sw strsCountSwitch) string {
+	n := -1
+	if sw.N != nil {
+		n = *sw.N
+	}
+	return strings.Replace(s, old, new, n)
+}
+
+func (sh strsHandlers) index(s, substr string) int {
+	i := strings.Index(s, substr)
+	if i < 0 {
+		return -1
+	}
+	return utf8.RuneCountInString(s[:i])
+}
+
+func (sh strsHandlers) substr(s string, start int, end ...int) string {
+	runes := []rune(s)
+
+	stop := len(runes)
+	if len(end) > 0 {
+		stop = end[0]
+	}
+
+	start, stop = strsClampPos(start, len(runes)), strsClampPos(stop, len(runes))
+	if start >= stop {
+		return ""
+	}
+	return string(runes[start:stop])
+}
+
+func (sh strsHandlers) title(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		if i == 0 || unicode.IsSpace(runes[i-1]) {
+			runes[i] = unicode.ToTitle(r)
+		}
+	}
+	return string(runes)
+}
+
+func (sh strsHandlers) repeat(s string, count int) (string, error) {
+	if count < 0 {
+		return "", fmt.Errorf("negative repeat count: %d", count)
+	}
+	return strings.Repeat(s, count), nil
+}
+
+func (sh strsHandlers) padLeft(s string, width int, pad ...string) string {
+	return strsPadding(s, width, pad) + s
+}
+
+func (sh strsHandlers) padRight(s string, width int, pad ...string) string {
+	return s + strsPadding(s, width, pad)
+}
+
+func (sh strsHandlers) format(format string, args ...any) string {
+	return fmt.Sprintf(format, args...)
+}
+
+func strsList(strs []string) []any {
+	l := make([]any, len(strs))
+	for i, s := range strs {
+		l[i] = s
+	}
+	return l
+}
+
+// strsClampPos converts a rune position, which may be negative to count from the end, into
+// an index between 0 and n inclusive.
+func strsClampPos(pos, n int) int {
+	if pos < 0 {
+		pos += n
+	}
+	return max(0, min(pos, n))
+}
+
+// strsPadding returns the padding required to make s width runes long.
+func strsPadding(s string, width int, pad []string) string {
+	padRunes := []rune(strings.Join(pad, ""))
+	if len(padRunes) == 0 {
+		padRunes = []rune{' '}
+	}
+
+	n := width - utf8.RuneCountInString(s)
+	if n <= 0 {
+		return ""
+	}
+
+	padding := make([]rune, n)
+	for i := range padding {
+		padding[i] = padRunes[i%len(padRunes)]
+	}
+	return string(padding)
+}
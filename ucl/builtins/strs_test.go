@@ -0,0 +1,80 @@
+package builtins_test
+
+import (
+	"context"
+	"testing"
+
+	"ucl.lmika.dev/ucl"
+	"ucl.lmika.dev/ucl/builtins"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrs(t *testing.T) {
+	tests := []struct {
+		descr   string
+		eval    string
+		want    any
+		wantErr bool
+	}{
+		{descr: "split", eval: `strs:split "a,b,,c" ","`, want: []any{"a", "b", "", "c"}},
+		{descr: "split limit", eval: `strs:split "a,b,c" "," -n 2`, want: []any{"a", "b,c"}},
+		{descr: "split runes", eval: `strs:split "héllo" ""`, want: []any{"h", "é", "l", "l", "o"}},
+		{descr: "split is native list", eval: `strs:split "a b" " " | append "c"`, want: []any{"a", "b", "c"}},
+		{descr: "join", eval: `strs:join ["a" "b" 3] ", "`, want: "a, b, 3"},
+		{descr: "join no sep", eval: `strs:join ["a" "b"]`, want: "ab"},
+		{descr: "fields", eval: `strs:fields "  one two\tthree\n"`, want: []any{"one", "two", "three"}},
+
+		{descr: "trim", eval: `strs:trim "  hello \n"`, want: "hello"},
+		{descr: "trim cutset", eval: `strs:trim "xxhixx" "x"`, want: "hi"},
+		{descr: "trimLeft", eval: `strs:trimLeft "  hello  "`, want: "hello  "},
+		{descr: "trimRight", eval: `strs:trimRight "  hello  "`, want: "  hello"},
+		{descr: "trimRight cutset", eval: `strs:trimRight "hello!!" "!"`, want: "hello"},
+		{descr: "trimPrefix", eval: `strs:trimPrefix "prefix-name" "prefix-"`, want: "name"},
+		{descr: "trimSuffix", eval: `strs:trimSuffix "file.txt" ".txt"`, want: "file"},
+
+		{descr: "replace", eval: `strs:replace "a-b-c" "-" "+"`, want: "a+b+c"},
+		{descr: "replace limit", eval: `strs:replace "a-b-c" "-" "+" -n 1`, want: "a+b-c"},
+		{descr: "contains", eval: `strs:contains "hello" "ell"`, want: true},
+		{descr: "not contains", eval: `strs:contains "hello" "xyz"`, want: false},
+		{descr: "hasPrefix", eval: `strs:hasPrefix "hello" "he"`, want: true},
+		{descr: "hasSuffix", eval: `strs:hasSuffix "hello" "he"`, want: false},
+		{descr: "index", eval: `strs:index "héllo" "l"`, want: 2},
+		{descr: "index missing", eval: `strs:index "hello" "z"`, want: -1},
+
+		{descr: "substr", eval: `strs:substr "héllo wörld" 6`, want: "wörld"},
+		{descr: "substr range", eval: `strs:substr "héllo" 1 3`, want: "él"},
+		{descr: "substr negative", eval: `strs:substr "héllo" -3 -1`, want: "ll"},
+		{descr: "substr out of range", eval: `strs:substr "abc" 2 10`, want: "c"},
+		{descr: "substr empty", eval: `strs:substr "abc" 2 1`, want: ""},
+
+		{descr: "toLower", eval: `strs:toLower "HÉLLO"`, want: "héllo"},
+		{descr: "toUpper", eval: `strs:toUpper "héllo"`, want: "HÉLLO"},
+		{descr: "title", eval: `strs:title "hello élan  vital"`, want: "Hello Élan  Vital"},
+		{descr: "repeat", eval: `strs:repeat "ab" 3`, want: "ababab"},
+		{descr: "repeat negative", eval: `strs:repeat "ab" -1`, wantErr: true},
+		{descr: "padLeft", eval: `strs:padLeft "é" 3`, want: "  é"},
+		{descr: "padLeft with pad", eval: `strs:padLeft "7" 3 "0"`, want: "007"},
+		{descr: "padRight", eval: `strs:padRight "ab" 5 "-="`, want: "ab-=-"},
+		{descr: "pad not needed", eval: `strs:padRight "abcdef" 3`, want: "abcdef"},
+
+		{descr: "format", eval: `strs:format "%-6s|%5d|%.2f" "héllo" 42 1.5`, want: "héllo |   42|1.50"},
+		{descr: "format list", eval: `strs:format "%v" [1 2]`, want: "[1 2]"},
+
+		{descr: "pipeline", eval: `"  A,b , C " | strs:trim | strs:toLower | strs:split "," | map { |x| strs:trim $x } | strs:join "-"`, want: "a-b-c"},
+		{descr: "wrong arg count", eval: `strs:contains "hello"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			inst := ucl.New(ucl.WithModule(builtins.Strs()))
+			res, err := inst.Eval(context.Background(), tt.eval)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+		})
+	}
+}
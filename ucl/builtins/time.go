@@ -0,0 +1,326 @@
+package builtins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ucl.lmika.dev/ucl"
+)
+
+// timeLayouts are the names of the layouts of Go's time package which can be used in place
+// of a layout string.
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"Stamp":       time.Stamp,
+	"StampMilli":  time.StampMilli,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+type timeHandlers struct {
+}
+
+// Time returns a module of functions for working with times and durations. Times and
+// durations are passed to Go builtins as time.Time and time.Duration values, and strings in
+// RFC 3339 format, or duration strings such as "1h30m", can be used in their place.
+//
+// The current time is taken from the clock of the instance, which can be set using
+// ucl.WithClock.
+func Time() ucl.Module {
+	th := timeHandlers{}
+
+	return ucl.Module{
+		Name: "time",
+		Builtins: map[string]ucl.BuiltinHandler{
+			"now":      th.now,
+			"since":    th.since,
+			"until":    th.until,
+			"parse":    ucl.Func(th.parse),
+			"format":   ucl.Func(th.format),
+			"date":     ucl.Func(th.date),
+			"duration": ucl.Func(time.ParseDuration),
+			"add":      ucl.Func(th.add),
+			"addDate":  ucl.Func(th.addDate),
+			"sub":      ucl.Func(th.sub),
+			"before":   ucl.Func(time.Time.Before),
+			"after":    ucl.Func(time.Time.After),
+			"compare":  ucl.Func(time.Time.Compare),
+			"truncate": ucl.Func(th.truncate),
+			"round":    ucl.Func(time.Time.Round),
+			"in":       ucl.Func(th.in),
+			"utc":      ucl.Func(time.Time.UTC),
+			"local":    ucl.Func(time.Time.Local),
+			"zone":     ucl.Func(th.zone),
+			"unix":     ucl.Func(th.unix),
+			"fromUnix": ucl.Func(th.fromUnix),
+			"parts":    ucl.Func(th.parts),
+			"seconds":  ucl.Func(time.Duration.Seconds),
+		},
+		Docs: map[string]ucl.Doc{
+			"now": {
+				Description: "Returns the current time.",
+				Switches:    []ucl.SwitchDoc{{Name: "utc", Description: "Return the time in UTC rather than the local time zone"}},
+			},
+			"since": {Description: "Returns the duration elapsed since TIME.", Args: []string{"TIME"}},
+			"until": {Description: "Returns the duration until TIME.", Args: []string{"TIME"}},
+			"parse": {
+				Description: "Parses STR as a time using LAYOUT, which defaults to RFC3339. LAYOUT is either a Go\nlayout string or the name of a layout of Go's time package, such as \"DateTime\" or \"Kitchen\".",
+				Args:        []string{"STR", "[LAYOUT]"},
+				Switches:    []ucl.SwitchDoc{{Name: "tz", Arg: "ZONE", Description: "Time zone of STR if it does not include one, rather than UTC"}},
+			},
+			"format": {
+				Description: "Formats TIME using LAYOUT, which defaults to RFC3339. See parse for the layouts.",
+				Args:        []string{"TIME", "[LAYOUT]"},
+			},
+			"date": {
+				Description: "Returns the time of the date and time of day, which defaults to midnight.",
+				Args:        []string{"YEAR", "MONTH", "DAY", "[HOUR]", "[MIN]", "[SEC]"},
+				Switches:    []ucl.SwitchDoc{{Name: "tz", Arg: "ZONE", Description: "Time zone of the date, rather than UTC"}},
+			},
+			"duration": {Description: "Parses STR as a duration, such as \"5m\" or \"1h30m\".", Args: []string{"STR"}},
+			"add": {
+				Description: "Returns TIME, or a duration, with each DURATION added. Durations can be negative.",
+				Args:        []string{"TIME", "DURATIONS..."},
+			},
+			"addDate": {Description: "Returns TIME with the years, months and days added.", Args: []string{"TIME", "YEARS", "[MONTHS]", "[DAYS]"}},
+			"sub": {
+				Description: "Returns the duration of TIME minus OTHER if OTHER is a time, or the time of TIME\nminus OTHER if it is a duration.",
+				Args:        []string{"TIME", "OTHER"},
+			},
+			"before":  {Description: "Returns true if TIME is before OTHER.", Args: []string{"TIME", "OTHER"}},
+			"after":   {Description: "Returns true if TIME is after OTHER.", Args: []string{"TIME", "OTHER"}},
+			"compare": {Description: "Returns -1 if TIME is before OTHER, 1 if it is after, or 0 if they are equal.", Args: []string{"TIME", "OTHER"}},
+			"truncate": {
+				Description: "Rounds TIME down to a multiple of UNIT since the zero time. UNIT is a duration, or one\nof \"day\", \"month\" or \"year\" to round down to the start of the day, month or year\nin the time zone of TIME.",
+				Args:        []string{"TIME", "UNIT"},
+			},
+			"round": {Description: "Rounds TIME to the nearest multiple of DURATION since the zero time.", Args: []string{"TIME", "DURATION"}},
+			"in":    {Description: "Returns TIME in the time zone ZONE, such as \"Australia/Melbourne\".", Args: []string{"TIME", "ZONE"}},
+			"utc":   {Description: "Returns TIME in UTC.", Args: []string{"TIME"}},
+			"local": {Description: "Returns TIME in the local time zone.", Args: []string{"TIME"}},
+			"zone":  {Description: "Returns the abbreviated name of the time zone of TIME.", Args: []string{"TIME"}},
+			"unix": {
+				Description: "Returns TIME as the number of seconds since January 1, 1970 UTC.",
+				Args:        []string{"TIME"},
+				Switches:    []ucl.SwitchDoc{{Name: "ms", Description: "Return the number of milliseconds"}},
+			},
+			"fromUnix": {
+				Description: "Returns the local time of N seconds since January 1, 1970 UTC.",
+				Args:        []string{"N"},
+				Switches:    []ucl.SwitchDoc{{Name: "ms", Description: "N is the number of milliseconds"}},
+			},
+			"parts": {
+				Description: "Returns a hash of the year, month, day, hour, minute, second, nanosecond, weekday,\nyearDay and zone of TIME.",
+				Args:        []string{"TIME"},
+			},
+			"seconds": {Description: "Returns DURATION as a float number of seconds.", Args: []string{"DURATION"}},
+		},
+	}
+}
+
+type timeZoneSwitch struct {
+	TZ *string `ucl:"-tz"`
+}
+
+// location returns the time zone of the -tz switch, or UTC if it was not given.
+func (sw timeZoneSwitch) location() (*time.Location, error) {
+	if sw.TZ == nil {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(*sw.TZ)
+}
+
+type timeUnixSwitch struct {
+	MS bool `ucl:"-ms"`
+}
+
+func (th timeHandlers) now(ctx context.Context, args ucl.CallArgs) (any, error) {
+	if args.HasSwitch("utc") {
+		return args.Now().UTC(), nil
+	}
+	return args.Now(), nil
+}
+
+func (th timeHandlers) since(ctx context.Context, args ucl.CallArgs) (any, error) {
+	var t time.Time
+	if err := args.Bind(&t); err != nil {
+		return nil, err
+	}
+	return args.Now().Sub(t), nil
+}
+
+func (th timeHandlers) until(ctx context.Context, args ucl.CallArgs) (any, error) {
+	var t time.Time
+	if err := args.Bind(&t); err != nil {
+		return nil, err
+	}
+	return t.Sub(args.Now()), nil
+}
+
+func (th timeHandlers) parse(s string, sw timeZoneSwitch, layout ...string) (time.Time, error) {
+	loc, err := sw.location()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(timeLayout(layout), s, loc)
+}
+
+func (th timeHandlers) format(t time.Time, layout ...string) string {
+	return t.Format(timeLayout(layout))
+}
+
+func (th timeHandlers) date(year, month, day int, sw timeZoneSwitch, tod ...int) (time.Time, error) {
+	if len(tod) > 3 {
+		return time.Time{}, errors.New("expected at most 6 args")
+	}
+
+	loc, err := sw.location()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	hms := make([]int, 3)
+	copy(hms, tod)
+	return time.Date(year, time.Month(month), day, hms[0], hms[1], hms[2], 0, loc), nil
+}
+
+func (th timeHandlers) add(base any, durs ...time.Duration) (any, error) {
+	var total time.Duration
+	for _, d := range durs {
+		total += d
+	}
+
+	switch t := base.(type) {
+	case time.Time:
+		return t.Add(total), nil
+	case time.Duration:
+		return t + total, nil
+	case string:
+		tv, err := time.Parse(time.RFC3339, t)
+		if err != nil {
+			return nil, err
+		}
+		return tv.Add(total), nil
+	}
+	return nil, fmt.Errorf("expected a time or duration but was %T", base)
+}
+
+func (th timeHandlers) addDate(t time.Time, years int, monthsDays ...int) (time.Time, error) {
+	if len(monthsDays) > 2 {
+		return time.Time{}, errors.New("expected at most 4 args")
+	}
+
+	md := make([]int, 2)
+	copy(md, monthsDays)
+	return t.AddDate(years, md[0], md[1]), nil
+}
+
+func (th timeHandlers) sub(t time.Time, other any) (any, error) {
+	switch o := other.(type) {
+	case time.Time:
+		return t.Sub(o), nil
+	case time.Duration:
+		return t.Add(-o), nil
+	case string:
+		if d, err := time.ParseDuration(o); err == nil {
+			return t.Add(-d), nil
+		}
+		ot, err := time.Parse(time.RFC3339, o)
+		if err != nil {
+			return nil, err
+		}
+		return t.Sub(ot), nil
+	}
+	return nil, fmt.Errorf("expected a time or duration but was %T", other)
+}
+
+func (th timeHandlers) truncate(t time.Time, unit any) (time.Time, error) {
+	switch u := unit.(type) {
+	case time.Duration:
+		return t.Truncate(u), nil
+	case string:
+		switch u {
+		case "day":
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()), nil
+		case "month":
+			return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()), nil
+		case "year":
+			return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location()), nil
+		}
+
+		d, err := time.ParseDuration(u)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return t.Truncate(d), nil
+	}
+	return time.Time{}, fmt.Errorf("expected a duration or unit but was %T", unit)
+}
+
+func (th timeHandlers) in(t time.Time, zone string) (time.Time, error) {
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(loc), nil
+}
+
+func (th timeHandlers) zone(t time.Time) string {
+	name, _ := t.Zone()
+	return name
+}
+
+func (th timeHandlers) unix(t time.Time, sw timeUnixSwitch) int {
+	if sw.MS {
+		return int(t.UnixMilli())
+	}
+	return int(t.Unix())
+}
+
+func (th timeHandlers) fromUnix(n int, sw timeUnixSwitch) time.Time {
+	if sw.MS {
+		return time.UnixMilli(int64(n))
+	}
+	return time.Unix(int64(n), 0)
+}
+
+func (th timeHandlers) parts(t time.Time) *ucl.OrderedHash {
+	zone, _ := t.Zone()
+
+	h := ucl.NewOrderedHash()
+	h.Set("year", t.Year())
+	h.Set("month", int(t.Month()))
+	h.Set("day", t.Day())
+	h.Set("hour", t.Hour())
+	h.Set("minute", t.Minute())
+	h.Set("second", t.Second())
+	h.Set("nanosecond", t.Nanosecond())
+	h.Set("weekday", t.Weekday().String())
+	h.Set("yearDay", t.YearDay())
+	h.Set("zone", zone)
+	return h
+}
+
+// timeLayout returns the layout named by the first element of layout, which defaults to
+// RFC3339.
+func timeLayout(layout []string) string {
+	if len(layout) == 0 {
+		return time.RFC3339
+	} else if l, ok := timeLayouts[layout[0]]; ok {
+		return l
+	}
+	return layout[0]
+}
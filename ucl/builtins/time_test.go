@@ -0,0 +1,141 @@
+package builtins_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"ucl.lmika.dev/ucl"
+	"ucl.lmika.dev/ucl/builtins"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTime(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 10, 30, 45, 0, time.UTC)
+	melb, err := time.LoadLocation("Australia/Melbourne")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		descr   string
+		eval    string
+		want    any
+		wantErr bool
+	}{
+		{descr: "now", eval: `time:now`, want: now},
+		{descr: "now utc", eval: `time:now -utc | time:zone`, want: "UTC"},
+		{descr: "since", eval: `time:since "2024-03-15T10:00:00Z"`, want: 30*time.Minute + 45*time.Second},
+		{descr: "until", eval: `time:until "2024-03-15T11:00:00Z"`, want: 29*time.Minute + 15*time.Second},
+
+		{descr: "parse", eval: `time:parse "2024-01-02T03:04:05Z"`, want: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{descr: "parse layout", eval: `time:parse "02/01/2024" "02/01/2006"`, want: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{descr: "parse named layout", eval: `time:parse "2024-01-02 03:04:05" "DateTime"`, want: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{descr: "parse tz", eval: `time:parse "2024-01-02" "DateOnly" -tz "Australia/Melbourne"`, want: time.Date(2024, 1, 2, 0, 0, 0, 0, melb)},
+		{descr: "parse bad", eval: `time:parse "yesterday"`, wantErr: true},
+		{descr: "format", eval: `time:now | time:format`, want: "2024-03-15T10:30:45Z"},
+		{descr: "format layout", eval: `time:now | time:format "Mon Jan 2 15:04"`, want: "Fri Mar 15 10:30"},
+		{descr: "format named layout", eval: `time:now | time:format "Kitchen"`, want: "10:30AM"},
+		{descr: "date", eval: `time:date 2024 2 29`, want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{descr: "date time", eval: `time:date 2024 2 29 13 14 15`, want: time.Date(2024, 2, 29, 13, 14, 15, 0, time.UTC)},
+		{descr: "date too many", eval: `time:date 2024 2 29 13 14 15 16`, wantErr: true},
+
+		{descr: "duration", eval: `time:duration "1h30m"`, want: 90 * time.Minute},
+		{descr: "duration bad", eval: `time:duration "soon"`, wantErr: true},
+		{descr: "add", eval: `time:now | time:add (time:duration "1h") "-15m"`, want: now.Add(45 * time.Minute)},
+		{descr: "add durations", eval: `time:add (time:duration "1h") "30m"`, want: 90 * time.Minute},
+		{descr: "addDate", eval: `time:now | time:addDate 0 1 1`, want: time.Date(2024, time.April, 16, 10, 30, 45, 0, time.UTC)},
+		{descr: "sub times", eval: `time:sub (time:now) "2024-03-15T00:00:00Z"`, want: 10*time.Hour + 30*time.Minute + 45*time.Second},
+		{descr: "sub duration", eval: `time:sub (time:now) "30m45s"`, want: time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)},
+
+		{descr: "before", eval: `time:before (time:now) "2025-01-01T00:00:00Z"`, want: true},
+		{descr: "after", eval: `time:after (time:now) "2025-01-01T00:00:00Z"`, want: false},
+		{descr: "compare", eval: `time:compare (time:now) (time:now)`, want: 0},
+		{descr: "eq", eval: `eq (time:now) (time:parse "2024-03-15T21:30:45+11:00")`, want: true},
+		{descr: "sort", eval: `[(time:now) (time:date 2020 1 1) (time:date 2030 1 1)] | sort | map { |t| time:format $t "2006" }`, want: []any{"2020", "2024", "2030"}},
+		{descr: "max durations", eval: `max [(time:duration "5m") (time:duration "1h") (time:duration "30s")]`, want: time.Hour},
+
+		{descr: "truncate", eval: `time:now | time:truncate "1h"`, want: time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)},
+		{descr: "truncate day", eval: `time:now | time:truncate "day"`, want: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{descr: "truncate month", eval: `time:now | time:truncate "month"`, want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{descr: "truncate year", eval: `time:now | time:truncate "year"`, want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{descr: "truncate day in zone", eval: `time:now | time:in "Australia/Melbourne" | time:truncate "day" | time:format`, want: "2024-03-15T00:00:00+11:00"},
+		{descr: "round", eval: `time:now | time:round "1m"`, want: time.Date(2024, 3, 15, 10, 31, 0, 0, time.UTC)},
+		{descr: "in", eval: `time:now | time:in "Australia/Melbourne" | time:format "15:04 MST"`, want: "21:30 AEDT"},
+		{descr: "in bad zone", eval: `time:now | time:in "Nowhere/Special"`, wantErr: true},
+		{descr: "utc", eval: `time:now | time:in "Australia/Melbourne" | time:utc | time:format`, want: "2024-03-15T10:30:45Z"},
+
+		{descr: "unix", eval: `time:now | time:unix`, want: 1710498645},
+		{descr: "unix ms", eval: `time:now | time:unix -ms`, want: 1710498645000},
+		{descr: "fromUnix", eval: `time:fromUnix 1710498645 | time:utc`, want: now},
+		{descr: "fromUnix ms", eval: `time:fromUnix 1710498645500 -ms | time:utc`, want: now.Add(500 * time.Millisecond)},
+		{descr: "parts", eval: `time:now | time:parts | values`, want: []any{2024, 3, 15, 10, 30, 45, 0, "Friday", 75, "UTC"}},
+		{descr: "seconds", eval: `time:duration "1m30s" | time:seconds`, want: 90.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			inst := ucl.New(
+				ucl.WithModule(builtins.Time()),
+				ucl.WithClock(func() time.Time { return now }),
+			)
+			res, err := inst.Eval(context.Background(), tt.eval)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				if wantTime, ok := tt.want.(time.Time); ok {
+					assert.True(t, wantTime.Equal(res.(time.Time)), "want %v but was %v", wantTime, res)
+				} else {
+					assert.Equal(t, tt.want, res)
+				}
+			}
+		})
+	}
+}
+
+func TestTime_GoValues(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 10, 30, 45, 0, time.UTC)
+
+	t.Run("bind to time and duration", func(t *testing.T) {
+		inst := ucl.New(ucl.WithModule(builtins.Time()), ucl.WithClock(func() time.Time { return now }))
+		inst.SetBuiltin("deadline", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+			var (
+				from time.Time
+				dur  time.Duration
+			)
+			if err := args.Bind(&from, &dur); err != nil {
+				return nil, err
+			}
+			return from.Add(dur).Format(time.Kitchen), nil
+		})
+
+		res, err := inst.Eval(context.Background(), `deadline (time:now) (time:duration "2h")`)
+		assert.NoError(t, err)
+		assert.Equal(t, "12:30PM", res)
+	})
+
+	t.Run("go values are times", func(t *testing.T) {
+		inst := ucl.New(ucl.WithModule(builtins.Time()))
+		inst.SetBuiltin("created", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+			return &now, nil
+		})
+		inst.SetBuiltin("timeout", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+			return 5 * time.Second, nil
+		})
+
+		res, err := inst.Eval(context.Background(), `cat (created | time:addDate 1) " " (timeout)`)
+		assert.NoError(t, err)
+		assert.Equal(t, "2025-03-15T10:30:45Z 5s", res)
+	})
+
+	t.Run("echo", func(t *testing.T) {
+		outW := bytes.NewBuffer(nil)
+		inst := ucl.New(ucl.WithOut(outW), ucl.WithModule(builtins.Time()), ucl.WithClock(func() time.Time { return now }))
+
+		_, err := inst.Eval(context.Background(), `echo (time:now) (time:duration "90s")`)
+		assert.NoError(t, err)
+		assert.Equal(t, "2024-03-15T10:30:45Z1m30s\n", outW.String())
+	})
+}
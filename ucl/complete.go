@@ -0,0 +1,448 @@
+package ucl
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// CompletionKind is the kind of thing a completion refers to.
+type CompletionKind int
+
+const (
+	CompletionCommand CompletionKind = iota
+	CompletionVariable
+	CompletionSwitch
+	CompletionKey
+)
+
+// Completion is a candidate for completing the word at the cursor.
+type Completion struct {
+	// Text is the completed word, which replaces the source from Start up to the cursor.
+	Text string
+
+	// Start is the byte offset in the source of the word being completed.
+	Start int
+
+	Kind CompletionKind
+
+	// Description is a short description of the completion, if one is available.
+	Description string
+}
+
+// Complete returns the candidates for completing the word of src ending at cursor, which is
+// a byte offset into src. Source after the cursor is ignored. Commands are proposed at the
+// start of a command, switches from the command documentation for words starting with "-",
+// variables for words starting with "$", and hash keys for "$var." paths.
+//
+// The source does not need to be complete: it is parsed with the word at the cursor replaced
+// by a placeholder, and any unclosed blocks, lists and sub-expressions closed. No completions
+// are returned if the cursor is within a string or comment, or if src cannot be parsed.
+func (inst *Inst) Complete(src string, cursor int) []Completion {
+	if cursor < 0 || cursor > len(src) {
+		cursor = len(src)
+	}
+
+	// A switch with no name yet, such as "-", is not a valid token so is split off before
+	// tokenising the rest of the source.
+	lexEnd := cursor
+	if dashes := strings.TrimRight(src[:cursor], "-"); len(dashes) < cursor && (dashes == "" || strings.ContainsAny(dashes[len(dashes)-1:], " \t")) {
+		lexEnd = len(dashes)
+	}
+
+	lex, err := scanner.LexString("complete", src[:lexEnd])
+	if err != nil {
+		return nil
+	}
+	toks, err := lexer.ConsumeAll(lex)
+	if err != nil {
+		return nil
+	}
+	if len(toks) > 0 && toks[len(toks)-1].EOF() {
+		toks = toks[:len(toks)-1]
+	}
+
+	items := groupCompletionItems(toks)
+
+	word := completionItem{tokType: completionWordItem, text: src[lexEnd:cursor], start: lexEnd}
+	if n := len(items); n > 0 && lexEnd == cursor {
+		last := items[n-1]
+		if last.end == cursor {
+			switch {
+			case last.tokType == completionWordItem:
+				word, items = last, items[:n-1]
+			case last.tokType == symCommentType || last.tokType == symStringType:
+				return nil
+			}
+		}
+	}
+
+	placeholder, ok := completionPlaceholder(word.text)
+	if !ok {
+		return nil
+	}
+
+	ast, err := parser.ParseString("complete", src[:word.start]+placeholder+completionClosers(items))
+	if err != nil {
+		return nil
+	}
+
+	w := &completionWalker{}
+	if ast.Statements == nil || !w.statements(ast.Statements, nil) {
+		return nil
+	}
+
+	var cs []Completion
+	switch w.site {
+	case completionSiteCommand:
+		cs = inst.completeCommands(w.procs, word.text, word.start)
+	case completionSiteVar:
+		cs = inst.completeVars(w.vars, word.text[1:], word.start)
+	case completionSiteKey:
+		base, prefix, _ := cutLast(word.text, ".")
+		cs = inst.completeKeys(base, prefix, cursor-len(prefix))
+	case completionSiteSwitch:
+		cs = inst.completeSwitches(w.cmd, word.text[1:], word.start)
+	}
+
+	sort.Slice(cs, func(i, j int) bool { return cs[i].Text < cs[j].Text })
+	return cs
+}
+
+func (inst *Inst) completeCommands(procs []string, prefix string, start int) []Completion {
+	names := append(inst.commandNames(), procs...)
+	sort.Strings(names)
+
+	var cs []Completion
+	for i, name := range names {
+		if !strings.HasPrefix(name, prefix) || (i > 0 && names[i-1] == name) {
+			continue
+		}
+		doc, _ := inst.Describe(name)
+		cs = append(cs, Completion{Text: name, Start: start, Kind: CompletionCommand, Description: doc.summary()})
+	}
+	return cs
+}
+
+func (inst *Inst) completeVars(scriptVars []string, prefix string, start int) []Completion {
+	seen := make(map[string]bool)
+	var cs []Completion
+	addVar := func(name string) {
+		if seen[name] || !strings.HasPrefix(name, prefix) {
+			return
+		}
+		seen[name] = true
+		cs = append(cs, Completion{Text: "$" + name, Start: start, Kind: CompletionVariable})
+	}
+
+	for _, name := range scriptVars {
+		addVar(name)
+	}
+	for ec := inst.rootEC; ec != nil; ec = ec.parent {
+		for name := range ec.vars {
+			addVar(name)
+		}
+	}
+	return cs
+}
+
+func (inst *Inst) completeSwitches(cmd string, prefix string, start int) []Completion {
+	doc, ok := inst.Describe(cmd)
+	if !ok {
+		return nil
+	}
+
+	var cs []Completion
+	for _, sw := range doc.Switches {
+		if strings.HasPrefix(sw.Name, prefix) {
+			cs = append(cs, Completion{Text: "-" + sw.Name, Start: start, Kind: CompletionSwitch, Description: sw.Description})
+		}
+	}
+	return cs
+}
+
+// completeKeys completes the keys of the hash referred to by path, a variable followed by
+// zero or more dot keys, such as "$config.server".
+func (inst *Inst) completeKeys(path string, prefix string, start int) []Completion {
+	parts := strings.Split(strings.TrimPrefix(path, "$"), ".")
+
+	val, ok := inst.rootEC.getVar(parts[0])
+	if !ok {
+		return nil
+	}
+	for _, key := range parts[1:] {
+		h, ok := val.(hashable)
+		if !ok {
+			return nil
+		}
+		val = h.Value(key)
+	}
+
+	h, ok := val.(hashable)
+	if !ok {
+		return nil
+	}
+
+	var cs []Completion
+	_ = h.Each(func(k string, v object) error {
+		if strings.HasPrefix(k, prefix) && identPattern.MatchString(k) {
+			cs = append(cs, Completion{Text: k, Start: start, Kind: CompletionKey})
+		}
+		return nil
+	})
+	return cs
+}
+
+var (
+	identPattern = regexp.MustCompile(`^[-]*[a-zA-Z_][\w-]*$`)
+
+	symWhitespaceType = scanner.Symbols()["Whitespace"]
+	symCommentType    = scanner.Symbols()["Comment"]
+	symStringType     = scanner.Symbols()["String"]
+
+	// completionWordItem is the type of a completion item made up of adjacent word tokens
+	completionWordItem = lexer.TokenType(-1000)
+
+	completionWordTypes = map[lexer.TokenType]bool{
+		scanner.Symbols()["Ident"]:  true,
+		scanner.Symbols()["Int"]:    true,
+		scanner.Symbols()["DOLLAR"]: true,
+		scanner.Symbols()["COLON"]:  true,
+		scanner.Symbols()["DOT"]:    true,
+	}
+)
+
+// completionItem is either a single punctuation token, or a word made up of adjacent
+// identifier, variable and dot tokens, such as "$a.b" or "fs:lines".
+type completionItem struct {
+	tokType lexer.TokenType
+	text    string
+	start   int
+	end     int
+}
+
+func groupCompletionItems(toks []lexer.Token) []completionItem {
+	var items []completionItem
+	for _, tok := range toks {
+		end := tok.Pos.Offset + len(tok.Value)
+		switch {
+		case tok.Type == symWhitespaceType:
+		case completionWordTypes[tok.Type]:
+			if n := len(items); n > 0 && items[n-1].tokType == completionWordItem && items[n-1].end == tok.Pos.Offset {
+				items[n-1].text += tok.Value
+				items[n-1].end = end
+				continue
+			}
+			items = append(items, completionItem{tokType: completionWordItem, text: tok.Value, start: tok.Pos.Offset, end: end})
+		default:
+			items = append(items, completionItem{tokType: tok.Type, text: tok.Value, start: tok.Pos.Offset, end: end})
+		}
+	}
+	return items
+}
+
+// completionPlaceholderIdent is the identifier which takes the place of the word being
+// completed when the source is parsed.
+const completionPlaceholderIdent = "ucl__complete__"
+
+// completionPlaceholder returns the source which replaces the word being completed, keeping
+// the parts of the word which determine what is being completed, such as the "$" of a
+// variable or the leading "-" of a switch.
+func completionPlaceholder(word string) (string, bool) {
+	switch {
+	case strings.HasPrefix(word, "$"):
+		if base, _, ok := cutLast(word, "."); ok {
+			return base + "." + completionPlaceholderIdent, true
+		}
+		return "$" + completionPlaceholderIdent, true
+	case strings.Contains(word, "."):
+		return "", false
+	case strings.HasPrefix(word, "-"):
+		return "-" + completionPlaceholderIdent, true
+	}
+	return completionPlaceholderIdent, true
+}
+
+// completionClosers returns the source which closes the blocks, block parameters, lists and
+// sub-expressions left open by items.
+func completionClosers(items []completionItem) string {
+	syms := scanner.Symbols()
+
+	var (
+		closers []string
+		afterLC bool
+	)
+	for _, item := range items {
+		switch item.tokType {
+		case syms["LC"]:
+			closers = append(closers, "}")
+			afterLC = true
+			continue
+		case syms["LP"]:
+			closers = append(closers, ")")
+		case syms["LS"]:
+			closers = append(closers, "]")
+		case syms["RC"], syms["RP"], syms["RS"]:
+			if len(closers) > 0 {
+				closers = closers[:len(closers)-1]
+			}
+		case syms["PIPE"]:
+			if n := len(closers); n > 0 && closers[n-1] == "|" {
+				closers = closers[:n-1]
+			} else if afterLC {
+				closers = append(closers, "|")
+			}
+		case syms["NL"]:
+			if afterLC {
+				continue
+			}
+		}
+		afterLC = false
+	}
+
+	var sb strings.Builder
+	for i := len(closers) - 1; i >= 0; i-- {
+		sb.WriteString(" ")
+		sb.WriteString(closers[i])
+	}
+	return sb.String()
+}
+
+type completionSite int
+
+const (
+	completionSiteNone completionSite = iota
+	completionSiteCommand
+	completionSiteVar
+	completionSiteKey
+	completionSiteSwitch
+)
+
+// completionWalker walks the AST looking for the placeholder, tracking the variables in
+// scope and the procs defined before it.
+type completionWalker struct {
+	procs []string
+
+	// site is what the placeholder is, along with the variables in scope of it, and the
+	// name of the command for switches
+	site completionSite
+	vars []string
+	cmd  string
+}
+
+func (w *completionWalker) statements(stmts *astStatements, vars []string) bool {
+	for _, p := range append([]*astPipeline{stmts.First}, stmts.Rest...) {
+		if w.pipeline(p, &vars) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *completionWalker) pipeline(p *astPipeline, vars *[]string) bool {
+	for _, cmd := range append([]*astCmd{p.First}, p.Rest...) {
+		if w.command(cmd, vars) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *completionWalker) command(cmd *astCmd, vars *[]string) bool {
+	var name string
+	if ident := cmd.Name.Arg.Ident; ident != nil && len(cmd.Name.DotSuffix) == 0 {
+		name = ident.String()
+	}
+
+	switch {
+	case name == completionPlaceholderIdent:
+		w.site, w.vars = completionSiteCommand, *vars
+		return true
+	case name == "set" || name == "proc":
+		if len(cmd.Args) > 0 && cmd.Args[0].Arg.Ident != nil && len(cmd.Args[0].DotSuffix) == 0 {
+			if n := cmd.Args[0].Arg.Ident.String(); name == "set" {
+				*vars = append(*vars, n)
+			} else {
+				w.procs = append(w.procs, n)
+			}
+		}
+	}
+
+	if w.dot(&cmd.Name, *vars) {
+		return true
+	}
+	for i := range cmd.Args {
+		if ident := cmd.Args[i].Arg.Ident; ident != nil && ident.String() == "-"+completionPlaceholderIdent {
+			if name != "" {
+				w.site, w.vars, w.cmd = completionSiteSwitch, *vars, name
+			}
+			return true
+		}
+		if w.dot(&cmd.Args[i], *vars) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *completionWalker) dot(d *astDot, vars []string) bool {
+	if v := d.Arg.Var; v != nil && len(d.DotSuffix) > 0 {
+		if last := d.DotSuffix[len(d.DotSuffix)-1].KeyIdent; last != nil && last.String() == completionPlaceholderIdent {
+			w.site, w.vars = completionSiteKey, vars
+			return true
+		}
+	}
+
+	if w.arg(&d.Arg, vars) {
+		return true
+	}
+	for _, ds := range d.DotSuffix {
+		if ds.Pipeline != nil && w.pipeline(ds.Pipeline, &vars) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *completionWalker) arg(a *astCmdArg, vars []string) bool {
+	switch {
+	case a.Ident != nil:
+		return a.Ident.String() == completionPlaceholderIdent
+	case a.Var != nil:
+		if a.Var.String() == completionPlaceholderIdent {
+			w.site, w.vars = completionSiteVar, vars
+			return true
+		}
+	case a.MaybeSub != nil && a.MaybeSub.Sub != nil:
+		return w.pipeline(a.MaybeSub.Sub, &vars)
+	case a.ListOrHash != nil:
+		for _, e := range a.ListOrHash.Elements {
+			if w.arg(&e.Left, vars) || (e.Right != nil && w.arg(e.Right, vars)) {
+				return true
+			}
+		}
+	case a.Block != nil:
+		for _, n := range a.Block.Names {
+			if n == completionPlaceholderIdent {
+				return true
+			}
+		}
+
+		blockVars := append(append([]string{}, vars...), a.Block.Names...)
+		for _, stmts := range a.Block.Statements {
+			if w.statements(stmts, blockVars) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
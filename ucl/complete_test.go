@@ -0,0 +1,120 @@
+package ucl_test
+
+import (
+	"context"
+	"testing"
+
+	"ucl.lmika.dev/ucl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInst_Complete(t *testing.T) {
+	newInst := func() *ucl.Inst {
+		inst := ucl.New(ucl.WithModule(ucl.Module{
+			Name: "fs",
+			Builtins: map[string]ucl.BuiltinHandler{
+				"lines": ucl.Func(func(name string) string { return name }),
+				"list":  ucl.Func(func(name string) string { return name }),
+			},
+			Docs: map[string]ucl.Doc{
+				"list": {
+					Description: "Lists files.",
+					Switches: []ucl.SwitchDoc{
+						{Name: "all", Description: "Include hidden files"},
+						{Name: "long", Description: "Use long format"},
+						{Name: "depth", Arg: "N", Description: "Max depth"},
+					},
+				},
+			},
+		}))
+		_, err := inst.Eval(context.Background(), `
+			set config [server:[host:"localhost" port:8080] "has space":1 debug:1]
+			set count 3
+			set name "test"
+		`)
+		assert.NoError(t, err)
+		return inst
+	}
+
+	tests := []struct {
+		desc   string
+		src    string
+		cursor int
+		want   []string
+		start  int
+	}{
+		{desc: "command prefix", src: "ec", want: []string{"echo"}},
+		{desc: "module commands", src: "fs:l", want: []string{"fs:lines", "fs:list"}},
+		{desc: "module prefix", src: "fs", want: []string{"fs:lines", "fs:list"}},
+		{desc: "command after pipe", src: `echo "a" | toU`, want: []string{"toUpper"}, start: 11},
+		{desc: "command after newline", src: "echo 1\nse", want: []string{"set", "set-at", "set-key"}, start: 7},
+		{desc: "command in block", src: `foreach [1 2] { |x| ec`, want: []string{"echo"}, start: 20},
+		{desc: "command in sub-expression", src: `echo (ca`, want: []string{"call", "cat"}, start: 6},
+		{desc: "procs in source", src: "proc greet { }; gre", want: []string{"greet"}, start: 16},
+		{desc: "command in hash value", src: `echo [a:(ec`, want: []string{"echo"}, start: 9},
+		{desc: "command in dot sub-expression", src: `echo $config.(ec`, want: []string{"echo"}, start: 14},
+		{desc: "procs in closed blocks", src: "if 1 { proc greet { } }; gre", want: []string{"greet"}, start: 25},
+		{desc: "no commands in args", src: "echo ec", want: nil},
+		{desc: "no commands in list", src: "echo [ec", want: nil},
+		{desc: "no commands in block params", src: "foreach [1] { |ec", want: nil},
+
+		{desc: "variables", src: "echo $c", want: []string{"$config", "$count"}, start: 5},
+		{desc: "all variables", src: "echo $", want: []string{"$config", "$count", "$hello", "$name"}, start: 5},
+		{desc: "variables in list", src: "echo [$n", want: []string{"$name"}, start: 6},
+		{desc: "variables set in source", src: "set cat 1; echo $ca", want: []string{"$cat"}, start: 16},
+		{desc: "block params", src: "foreach [1] { |cat dog| echo $ca", want: []string{"$cat"}, start: 29},
+		{desc: "block params over lines", src: "foreach [1] {\n  |cat|\n  echo $ca", want: []string{"$cat"}, start: 29},
+		{desc: "variables set in enclosing block", src: "foreach [1] { |x| set cat 1; echo ($ca", want: []string{"$cat"}, start: 35},
+		{desc: "variables set in closed block", src: "foreach [1] { |x| set cat 1 }; echo $ca", want: nil},
+		{desc: "block params out of scope", src: "foreach [1] { |cat dog| }; echo $ca", want: nil},
+
+		{desc: "hash keys", src: "echo $config.", want: []string{"debug", "server"}, start: 13},
+		{desc: "hash keys with prefix", src: "echo $config.s", want: []string{"server"}, start: 13},
+		{desc: "nested hash keys", src: "echo $config.server.", want: []string{"host", "port"}, start: 20},
+		{desc: "keys of non-hash", src: "echo $count.", want: nil},
+		{desc: "keys of missing var", src: "echo $missing.", want: nil},
+
+		{desc: "switches", src: "fs:list -", want: []string{"-all", "-depth", "-long"}, start: 8},
+		{desc: "switches with prefix", src: "fs:list -l", want: []string{"-long"}, start: 8},
+		{desc: "switches after args", src: `fs:list "." -d`, want: []string{"-depth"}, start: 12},
+		{desc: "switches of undocumented command", src: "fs:lines -", want: nil},
+
+		{desc: "unparsable", src: "echo )) ec", want: nil},
+		{desc: "within string", src: `echo "ec`, want: nil},
+		{desc: "after string", src: `echo "ec"`, want: nil},
+		{desc: "within comment", src: `echo # ec`, want: nil},
+		{desc: "cursor in middle", src: "ec; toUpper", cursor: 2, want: []string{"echo"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			inst := newInst()
+
+			cursor := len(tt.src)
+			if tt.cursor > 0 {
+				cursor = tt.cursor
+			}
+
+			cs := inst.Complete(tt.src, cursor)
+
+			var texts []string
+			for _, c := range cs {
+				texts = append(texts, c.Text)
+				assert.Equal(t, tt.start, c.Start)
+			}
+			assert.Equal(t, tt.want, texts)
+		})
+	}
+
+	t.Run("descriptions", func(t *testing.T) {
+		inst := newInst()
+
+		assert.Equal(t, []ucl.Completion{
+			{Text: "fs:list", Kind: ucl.CompletionCommand, Description: "Lists files."},
+		}, inst.Complete("fs:lis", 6))
+		assert.Equal(t, []ucl.Completion{
+			{Text: "-all", Start: 8, Kind: ucl.CompletionSwitch, Description: "Include hidden files"},
+		}, inst.Complete("fs:list -a", 10))
+	})
+}
@@ -0,0 +1,255 @@
+package ucl
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	invokableType       = reflect.TypeOf(Invokable{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// toGoReflectValue converts an object into a reflect value of the given type. Objects backed
+// by Go values, such as proxies and opaques, are used as is if they are assignable to t.
+// Otherwise, a conversion is attempted based on the kind of t. Invokable objects are wrapped
+// as an Invokable bound to the evaluation context of ia.
+func (ia invocationArgs) toGoReflectValue(obj object, t reflect.Type) (reflect.Value, error) {
+	if obj == nil {
+		return reflect.Zero(t), nil
+	}
+
+	if rv, ok := goReflectValueOf(obj); ok {
+		for {
+			if rv.Type().AssignableTo(t) {
+				return rv, nil
+			} else if rv.Kind() != reflect.Pointer || rv.IsNil() {
+				break
+			}
+			rv = rv.Elem()
+		}
+	}
+
+	switch {
+	case t == invokableType:
+		if inv, ok := obj.(invokable); ok && ia.inst != nil {
+			return reflect.ValueOf(ia.invokable(inv)), nil
+		}
+		return reflect.Value{}, conversionError(obj, t)
+	case t == durationType:
+		if s, ok := obj.(strObject); ok {
+			d, err := time.ParseDuration(string(s))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(d), nil
+		}
+		return reflect.Value{}, conversionError(obj, t)
+	case t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(textUnmarshalerType):
+		if s, ok := obj.(strObject); ok {
+			rv := reflect.New(t)
+			if err := rv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+				return reflect.Value{}, err
+			}
+			return rv.Elem(), nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		gv, ok := toGoValueWith(obj, ia.orderedHashes())
+		if inv, isInv := obj.(invokable); !ok && isInv && ia.inst != nil {
+			gv, ok = ia.invokable(inv), true
+		}
+		if !ok {
+			break
+		}
+		if gv == nil {
+			return reflect.Zero(t), nil
+		} else if rv := reflect.ValueOf(gv); rv.Type().AssignableTo(t) {
+			return rv, nil
+		}
+	case reflect.String:
+		if s, ok := obj.(strObject); ok {
+			return reflect.ValueOf(string(s)).Convert(t), nil
+		}
+	case reflect.Bool:
+		if b, ok := obj.(boolObject); ok {
+			return reflect.ValueOf(bool(b)).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := obj.(intObject); ok {
+			rv := reflect.New(t).Elem()
+			if rv.OverflowInt(int64(i)) {
+				return reflect.Value{}, fmt.Errorf("%v overflows %v", int(i), t)
+			}
+			rv.SetInt(int64(i))
+			return rv, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, ok := obj.(intObject); ok {
+			rv := reflect.New(t).Elem()
+			if i < 0 || rv.OverflowUint(uint64(i)) {
+				return reflect.Value{}, fmt.Errorf("%v overflows %v", int(i), t)
+			}
+			rv.SetUint(uint64(i))
+			return rv, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch n := obj.(type) {
+		case intObject:
+			return reflect.ValueOf(float64(n)).Convert(t), nil
+		case floatObject:
+			return reflect.ValueOf(float64(n)).Convert(t), nil
+		case strObject:
+			// Infinities and NaN are accepted as strings, as that is how repr writes them
+			if f, err := strconv.ParseFloat(string(n), 64); err == nil && (math.IsInf(f, 0) || math.IsNaN(f)) {
+				return reflect.ValueOf(f).Convert(t), nil
+			}
+		}
+	case reflect.Slice:
+		l, ok := obj.(listable)
+		if !ok {
+			break
+		}
+		rv := reflect.MakeSlice(t, l.Len(), l.Len())
+		for i := 0; i < l.Len(); i++ {
+			ev, err := ia.toGoReflectValue(l.Index(i), t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			rv.Index(i).Set(ev)
+		}
+		return rv, nil
+	case reflect.Map:
+		h, ok := obj.(hashable)
+		if !ok || t.Key().Kind() != reflect.String {
+			break
+		}
+		rv := reflect.MakeMapWithSize(t, h.Len())
+		if err := h.Each(func(k string, v object) error {
+			ev, err := ia.toGoReflectValue(v, t.Elem())
+			if err != nil {
+				return fmt.Errorf("key '%v': %w", k, err)
+			}
+			rv.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), ev)
+			return nil
+		}); err != nil {
+			return reflect.Value{}, err
+		}
+		return rv, nil
+	case reflect.Struct:
+		h, ok := obj.(hashable)
+		if !ok {
+			break
+		}
+		rv := reflect.New(t).Elem()
+		for _, f := range reflect.VisibleFields(t) {
+			if !f.IsExported() || f.Anonymous {
+				continue
+			}
+
+			key := f.Name
+			if tag, ok := f.Tag.Lookup("ucl"); ok {
+				key = tag
+			}
+
+			hv := h.Value(key)
+			if hv == nil {
+				continue
+			}
+
+			ev, err := ia.toGoReflectValue(hv, f.Type)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field '%v': %w", key, err)
+			}
+			fv, err := rv.FieldByIndexErr(f.Index)
+			if err != nil {
+				continue
+			}
+			fv.Set(ev)
+		}
+		return rv, nil
+	case reflect.Pointer:
+		ev, err := ia.toGoReflectValue(obj, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		rv := reflect.New(t.Elem())
+		rv.Elem().Set(ev)
+		return rv, nil
+	}
+
+	return reflect.Value{}, conversionError(obj, t)
+}
+
+func conversionError(obj object, t reflect.Type) error {
+	return fmt.Errorf("expected %v but was %v", t, typeName(obj))
+}
+
+// goReflectValueOf returns the Go value backing an object, if any.
+func goReflectValueOf(obj object) (reflect.Value, bool) {
+	switch t := obj.(type) {
+	case OpaqueObject:
+		if t.v == nil {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(t.v), true
+	case timeObject:
+		return reflect.ValueOf(time.Time(t)), true
+	case durationObject:
+		return reflect.ValueOf(time.Duration(t)), true
+	case proxyObject:
+		if t.p == nil {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(t.p), true
+	case listableProxyObject:
+		return t.orig, true
+	case structProxyObject:
+		return t.orig, true
+	case mapProxyObject:
+		return t.orig, true
+	}
+	return reflect.Value{}, false
+}
+
+// typeName returns a short, user facing name of the type of obj for use in error messages.
+func typeName(obj object) string {
+	switch obj.(type) {
+	case nil:
+		return "nil"
+	case strObject:
+		return "string"
+	case intObject:
+		return "int"
+	case floatObject:
+		return "float"
+	case boolObject:
+		return "bool"
+	case *listObject:
+		return "list"
+	case *hashObject:
+		return "hash"
+	case blockObject:
+		return "block"
+	case procObject:
+		return "proc"
+	case Iterator:
+		return "iterator"
+	case timeObject:
+		return "time"
+	case durationObject:
+		return "duration"
+	}
+
+	if rv, ok := goReflectValueOf(obj); ok {
+		return rv.Type().String()
+	}
+	return fmt.Sprintf("%T", obj)
+}
@@ -0,0 +1,266 @@
+package ucl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lmika/gopkgs/fp/slices"
+)
+
+// Displayer writes the result of an evaluation to an output. Results are passed to Display as
+// Go values, in the same form as they are returned from Inst.Eval.
+type Displayer interface {
+	Display(w io.Writer, v any) error
+}
+
+// Displayable can be implemented by Go types to control how they are displayed by the plain,
+// table and tree displayers.
+type Displayable interface {
+	Display(w io.Writer) error
+}
+
+// WithDisplayer sets the displayer used by EvalAndDisplay. The default is PlainDisplayer.
+func WithDisplayer(d Displayer) InstOption {
+	return func(i *Inst) {
+		i.displayer = d
+	}
+}
+
+// Displayer returns the displayer used by EvalAndDisplay.
+func (inst *Inst) Displayer() Displayer {
+	if inst.displayer == nil {
+		return PlainDisplayer{}
+	}
+	return inst.displayer
+}
+
+// PlainDisplayer displays each element of a list on a separate line, and other values using
+// their Go formatting. Nil is displayed as "(nil)".
+type PlainDisplayer struct{}
+
+func (pd PlainDisplayer) Display(w io.Writer, v any) error {
+	if d, ok := v.(Displayable); ok {
+		return d.Display(w)
+	} else if v == nil {
+		_, err := fmt.Fprintln(w, "(nil)")
+		return err
+	}
+
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			if err := pd.Display(w, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	_, err := fmt.Fprintln(w, v)
+	return err
+}
+
+// TableDisplayer displays a list of hashes or structs as a table, with a column for each key.
+// A single hash or struct is displayed as a table of keys and values. Anything else is
+// displayed using PlainDisplayer.
+type TableDisplayer struct{}
+
+func (TableDisplayer) Display(w io.Writer, v any) error {
+	if d, ok := v.(Displayable); ok {
+		return d.Display(w)
+	}
+
+	rv := displayIndirect(reflect.ValueOf(v))
+	if entries, ok := displayEntries(rv); ok {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, e := range entries {
+			fmt.Fprintf(tw, "%v\t%v\n", e.key, displayCell(e.val))
+		}
+		return tw.Flush()
+	}
+
+	if rv.Kind() != reflect.Slice || rv.Len() == 0 {
+		return PlainDisplayer{}.Display(w, v)
+	}
+
+	var (
+		cols    []string
+		seen    = make(map[string]bool)
+		records = make([]map[string]reflect.Value, rv.Len())
+	)
+	for i := range records {
+		entries, ok := displayEntries(displayIndirect(rv.Index(i)))
+		if !ok {
+			return PlainDisplayer{}.Display(w, v)
+		}
+
+		records[i] = make(map[string]reflect.Value)
+		for _, e := range entries {
+			if !seen[e.key] {
+				seen[e.key] = true
+				cols = append(cols, e.key)
+			}
+			records[i][e.key] = e.val
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(cols, "\t"))
+	for _, rec := range records {
+		fmt.Fprintln(tw, strings.Join(slices.Map(cols, func(c string) string {
+			if v, ok := rec[c]; ok {
+				return displayCell(v)
+			}
+			return ""
+		}), "\t"))
+	}
+	return tw.Flush()
+}
+
+// TreeDisplayer displays nested hashes, structs and lists as an indented tree, with each key
+// or list element on a separate line.
+type TreeDisplayer struct{}
+
+func (td TreeDisplayer) Display(w io.Writer, v any) error {
+	if d, ok := v.(Displayable); ok {
+		return d.Display(w)
+	}
+	return td.writeTree(w, reflect.ValueOf(v), "")
+}
+
+func (td TreeDisplayer) writeTree(w io.Writer, rv reflect.Value, indent string) error {
+	rv = displayIndirect(rv)
+
+	if entries, ok := displayEntries(rv); ok && len(entries) > 0 {
+		for _, e := range entries {
+			if err := td.writeNode(w, e.val, indent, e.key+":"); err != nil {
+				return err
+			}
+		}
+		return nil
+	} else if rv.Kind() == reflect.Slice && rv.Len() > 0 {
+		for i := 0; i < rv.Len(); i++ {
+			if err := td.writeNode(w, rv.Index(i), indent, "-"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	_, err := fmt.Fprintf(w, "%v%v\n", indent, displayCell(rv))
+	return err
+}
+
+func (td TreeDisplayer) writeNode(w io.Writer, rv reflect.Value, indent string, label string) error {
+	if !displayIsNested(displayIndirect(rv)) {
+		_, err := fmt.Fprintf(w, "%v%v %v\n", indent, label, displayCell(rv))
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "%v%v\n", indent, label); err != nil {
+		return err
+	}
+	return td.writeTree(w, rv, indent+"  ")
+}
+
+// JSONDisplayer displays values as JSON, indented by Indent if it is not empty.
+type JSONDisplayer struct {
+	Indent string
+}
+
+func (jd JSONDisplayer) Display(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", jd.Indent)
+	return enc.Encode(v)
+}
+
+type displayEntry struct {
+	key string
+	val reflect.Value
+}
+
+// displayEntries returns the keys and values of an OrderedHash in order, a map with string
+// keys sorted by key, or the exported fields of a struct.
+func displayEntries(rv reflect.Value) ([]displayEntry, bool) {
+	if oh, ok := orderedHashOf(rv); ok {
+		entries := make([]displayEntry, 0, oh.Len())
+		for _, k := range oh.keys {
+			entries = append(entries, displayEntry{key: k, val: reflect.ValueOf(oh.vals[k])})
+		}
+		return entries, true
+	}
+
+	switch {
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		entries := make([]displayEntry, 0, rv.Len())
+		for iter := rv.MapRange(); iter.Next(); {
+			entries = append(entries, displayEntry{key: iter.Key().String(), val: iter.Value()})
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+		return entries, true
+	case rv.Kind() == reflect.Struct && rv.Type() != invokableType:
+		var entries []displayEntry
+		for _, f := range reflect.VisibleFields(rv.Type()) {
+			if !f.IsExported() {
+				continue
+			}
+			if fv, err := rv.FieldByIndexErr(f.Index); err == nil {
+				entries = append(entries, displayEntry{key: f.Name, val: fv})
+			}
+		}
+		return entries, true
+	}
+	return nil, false
+}
+
+func displayIsNested(rv reflect.Value) bool {
+	if entries, ok := displayEntries(rv); ok {
+		return len(entries) > 0
+	}
+	return rv.Kind() == reflect.Slice && rv.Len() > 0
+}
+
+// displayIndirect follows pointers and interfaces to the underlying value, stopping at values
+// which implement Displayable or fmt.Stringer.
+func displayIndirect(rv reflect.Value) reflect.Value {
+	for rv.IsValid() && (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) && !rv.IsNil() {
+		if rv.CanInterface() && rv.Kind() == reflect.Pointer {
+			switch rv.Interface().(type) {
+			case Displayable, fmt.Stringer:
+				return rv
+			}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// displayCell returns the text of a value displayed within a table or tree.
+func displayCell(rv reflect.Value) string {
+	rv = displayIndirect(rv)
+	if !rv.IsValid() || ((rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) && rv.IsNil()) {
+		return "(nil)"
+	} else if !rv.CanInterface() {
+		return rv.String()
+	}
+
+	switch v := rv.Interface().(type) {
+	case Displayable:
+		sb := strings.Builder{}
+		if err := v.Display(&sb); err != nil {
+			return err.Error()
+		}
+		return strings.TrimSuffix(sb.String(), "\n")
+	}
+
+	if entries, ok := displayEntries(rv); ok && len(entries) == 0 {
+		return "[:]"
+	} else if rv.Kind() == reflect.Slice && rv.Len() == 0 {
+		return "[]"
+	}
+	return fmt.Sprint(rv.Interface())
+}
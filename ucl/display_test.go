@@ -0,0 +1,119 @@
+package ucl_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"ucl.lmika.dev/ucl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type displayUser struct {
+	Name  string
+	Age   int
+	email string
+}
+
+type displayBadge string
+
+func (b displayBadge) Display(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "<%v>\n", string(b))
+	return err
+}
+
+func TestEvalAndDisplay(t *testing.T) {
+	lines := func(ls ...string) string {
+		return strings.Join(ls, "\n") + "\n"
+	}
+
+	tests := []struct {
+		desc      string
+		displayer ucl.Displayer
+		expr      string
+		want      string
+	}{
+		{desc: "plain nil", expr: `set x ()`, want: "(nil)\n"},
+		{desc: "plain string", expr: `cat "hello"`, want: "hello\n"},
+		{desc: "plain list", expr: `["a" ["b" "c"] 1]`, want: lines("a", "b", "c", "1")},
+		{desc: "plain hash", expr: `[b:1 a:"x"]`, want: "map[a:x b:1]\n"},
+		{desc: "plain bool", expr: `eq 1 1`, want: "true\n"},
+		{desc: "plain proc", expr: `proc x { }`, want: "(proc)\n"},
+		{desc: "plain displayable", expr: `badge "gold"`, want: "<gold>\n"},
+		{desc: "plain go slice", expr: `users`, want: lines("{Alice 30 a@example.com}", "{Bob 25 b@example.com}")},
+
+		{desc: "table list of hashes", displayer: ucl.TableDisplayer{}, expr: `[[name:"a" n:1] [name:"bb" n:22 ok:(eq 1 1)]]`, want: lines(
+			"n   name  ok",
+			"1   a     ",
+			"22  bb    true",
+		)},
+		{desc: "table list of structs", displayer: ucl.TableDisplayer{}, expr: `users`, want: lines(
+			"Name   Age",
+			"Alice  30",
+			"Bob    25",
+		)},
+		{desc: "table single hash", displayer: ucl.TableDisplayer{}, expr: `[name:"a" tags:[] attrs:[:]]`, want: lines(
+			"attrs  [:]",
+			"name   a",
+			"tags   []",
+		)},
+		{desc: "table non-records", displayer: ucl.TableDisplayer{}, expr: `["a" "b"]`, want: lines("a", "b")},
+		{desc: "table displayable cell", displayer: ucl.TableDisplayer{}, expr: `[[b:(badge "x")]]`, want: lines("b", "<x>")},
+
+		{desc: "tree nested", displayer: ucl.TreeDisplayer{}, expr: `[server:[host:"h" ports:[1 2]] name:"n" none:[]]`, want: lines(
+			"name: n",
+			"none: []",
+			"server:",
+			"  host: h",
+			"  ports:",
+			"    - 1",
+			"    - 2",
+		)},
+		{desc: "tree list of structs", displayer: ucl.TreeDisplayer{}, expr: `users`, want: lines(
+			"-",
+			"  Name: Alice",
+			"  Age: 30",
+			"-",
+			"  Name: Bob",
+			"  Age: 25",
+		)},
+		{desc: "tree scalar", displayer: ucl.TreeDisplayer{}, expr: `cat "hello"`, want: "hello\n"},
+
+		{desc: "repr string", displayer: ucl.ReprDisplayer{}, expr: `cat "hello"`, want: "hello\n"},
+		{desc: "repr nil", displayer: ucl.ReprDisplayer{}, expr: `set x ()`, want: ""},
+		{desc: "repr list", displayer: ucl.ReprDisplayer{}, expr: `["a" [b:1]]`, want: `["a" [b:1]]` + "\n"},
+		{desc: "repr displayable", displayer: ucl.ReprDisplayer{}, expr: `badge "gold"`, want: "<gold>\n"},
+
+		{desc: "json", displayer: ucl.JSONDisplayer{}, expr: `[b:[1 2] a:"x" c:(eq 1 2)]`, want: `{"a":"x","b":[1,2],"c":false}` + "\n"},
+		{desc: "json indented", displayer: ucl.JSONDisplayer{Indent: "  "}, expr: `[1 2]`, want: "[\n  1,\n  2\n]\n"},
+		{desc: "json structs", displayer: ucl.JSONDisplayer{}, expr: `users`, want: `[{"Name":"Alice","Age":30},{"Name":"Bob","Age":25}]` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ctx := context.Background()
+			outW := bytes.NewBuffer(nil)
+
+			opts := []ucl.InstOption{ucl.WithOut(outW)}
+			if tt.displayer != nil {
+				opts = append(opts, ucl.WithDisplayer(tt.displayer))
+			}
+			inst := ucl.New(opts...)
+			inst.SetFunc("users", func() []displayUser {
+				return []displayUser{
+					{Name: "Alice", Age: 30, email: "a@example.com"},
+					{Name: "Bob", Age: 25, email: "b@example.com"},
+				}
+			})
+			inst.SetFunc("badge", func(s string) displayBadge { return displayBadge(s) })
+
+			err := ucl.EvalAndDisplay(ctx, inst, tt.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, outW.String())
+		})
+	}
+}
@@ -6,6 +6,11 @@ type evalCtx struct {
 	commands map[string]invokable
 	macros   map[string]macroable
 	vars     map[string]object
+	consts   map[string]bool
+
+	// modDir is the directory of the script module evaluated in this root, which the paths
+	// of imports within the module are resolved against.
+	modDir string
 }
 
 func (ec *evalCtx) forkAndIsolate() *evalCtx {
@@ -58,11 +63,24 @@ func (ec *evalCtx) setOrDefineVar(name string, val object) {
 	ec.vars[name] = val
 }
 
-func (ec *evalCtx) getVar(name string) (object, bool) {
-	if ec.vars == nil {
-		return nil, false
+func (ec *evalCtx) defineConst(name string, val object) {
+	if ec.consts == nil {
+		ec.consts = make(map[string]bool)
+	}
+	ec.consts[name] = true
+	ec.setOrDefineVar(name, val)
+}
+
+func (ec *evalCtx) isConst(name string) bool {
+	for e := ec; e != nil; e = e.parent {
+		if e.consts[name] {
+			return true
+		}
 	}
+	return false
+}
 
+func (ec *evalCtx) getVar(name string) (object, bool) {
 	if v, ok := ec.vars[name]; ok {
 		return v, true
 	} else if ec.parent != nil {
@@ -7,7 +7,8 @@ import (
 )
 
 type evaluator struct {
-	inst *Inst
+	inst   *Inst
+	script *astScript
 }
 
 func (e evaluator) evalBlock(ctx context.Context, ec *evalCtx, n *astBlock) (lastRes object, err error) {
@@ -23,6 +24,7 @@ func (e evaluator) evalBlock(ctx context.Context, ec *evalCtx, n *astBlock) (las
 }
 
 func (e evaluator) evalScript(ctx context.Context, ec *evalCtx, n *astScript) (lastRes object, err error) {
+	e.script = n
 	return e.evalStatement(ctx, ec, n.Statements)
 }
 
@@ -107,9 +109,10 @@ func (e evaluator) evalCmd(ctx context.Context, ec *evalCtx, currentPipe object,
 
 func (e evaluator) evalInvokable(ctx context.Context, ec *evalCtx, currentPipe object, ast *astCmd, cmd invokable) (object, error) {
 	var (
-		pargs   listObject
-		kwargs  map[string]*listObject
-		argsPtr *listObject
+		pargs    listObject
+		kwargs   map[string]*listObject
+		switches []switchArg
+		argsPtr  *listObject
 	)
 
 	argsPtr = &pargs
@@ -123,8 +126,10 @@ func (e evaluator) evalInvokable(ctx context.Context, ec *evalCtx, currentPipe o
 				kwargs = make(map[string]*listObject)
 			}
 
+			name := ident.String()[1:]
 			argsPtr = &listObject{}
-			kwargs[ident.String()[1:]] = argsPtr
+			kwargs[name] = argsPtr
+			switches = append(switches, switchArg{name: name, args: argsPtr})
 		} else {
 			ae, err := e.evalDot(ctx, ec, arg)
 			if err != nil {
@@ -134,7 +139,7 @@ func (e evaluator) evalInvokable(ctx context.Context, ec *evalCtx, currentPipe o
 		}
 	}
 
-	invArgs := invocationArgs{eval: e, ec: ec, inst: e.inst, args: pargs, kwargs: kwargs}
+	invArgs := invocationArgs{eval: e, ec: ec, inst: e.inst, args: pargs.elems, kwargs: kwargs, switches: switches}
 	return cmd.invoke(ctx, invArgs)
 }
 
@@ -182,7 +187,7 @@ func (e evaluator) evalArg(ctx context.Context, ec *evalCtx, n astCmdArg) (objec
 	case n.Ident != nil:
 		return strObject(n.Ident.String()), nil
 	case n.Var != nil:
-		if v, ok := ec.getVar(*n.Var); ok {
+		if v, ok := ec.getVar(n.Var.String()); ok {
 			return v, nil
 		}
 		return nil, nil
@@ -202,13 +207,13 @@ func (e evaluator) evalArg(ctx context.Context, ec *evalCtx, n astCmdArg) (objec
 
 func (e evaluator) evalListOrHash(ctx context.Context, ec *evalCtx, loh *astListOrHash) (object, error) {
 	if loh.EmptyList {
-		return listObject{}, nil
+		return &listObject{}, nil
 	} else if loh.EmptyHash {
-		return hashObject{}, nil
+		return newHashObject(0), nil
 	}
 
 	if firstIsHash := loh.Elements[0].Right != nil; firstIsHash {
-		h := hashObject{}
+		h := newHashObject(len(loh.Elements))
 		for _, el := range loh.Elements {
 			if el.Right == nil {
 				return nil, errors.New("miss-match of lists and hash")
@@ -224,12 +229,12 @@ func (e evaluator) evalListOrHash(ctx context.Context, ec *evalCtx, loh *astList
 				return nil, err
 			}
 
-			h[n.String()] = v
+			h.set(n.String(), v)
 		}
 		return h, nil
 	}
 
-	l := listObject{}
+	l := &listObject{}
 	for _, el := range loh.Elements {
 		if el.Right != nil {
 			return nil, errors.New("miss-match of lists and hash")
@@ -238,7 +243,7 @@ func (e evaluator) evalListOrHash(ctx context.Context, ec *evalCtx, loh *astList
 		if err != nil {
 			return nil, err
 		}
-		l = append(l, v)
+		l.Append(v)
 	}
 	return l, nil
 }
@@ -251,6 +256,8 @@ func (e evaluator) evalLiteral(ctx context.Context, ec *evalCtx, n *astLiteral)
 			return nil, err
 		}
 		return strObject(uq), nil
+	case n.Float != nil:
+		return floatObject(*n.Float), nil
 	case n.Int != nil:
 		return intObject(*n.Int), nil
 	}
@@ -0,0 +1,168 @@
+package ucl
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// SetFunc registers fn as a builtin with the given name. See Func for how fn is called.
+func (inst *Inst) SetFunc(name string, fn any) {
+	inst.SetBuiltin(name, Func(fn))
+}
+
+// Func returns a BuiltinHandler which calls fn, a plain Go function.
+//
+// The first parameter of fn may be a context.Context. The remaining parameters are bound from
+// the positional arguments using CallArgs.Bind. If the function is variadic, any remaining
+// arguments are bound to the variadic parameter. A struct parameter, appearing last or just
+// before the variadic parameter, with fields tagged as `ucl:"-name"` will be populated from
+// the switches of the invocation. An error is returned if a switch does not match a field, or
+// is given the wrong number of arguments.
+//
+// The function can return nothing, a single value, an error, or a value and an error.
+//
+// Func panics if fn is not a function or does not follow these conventions.
+func Func(fn any) BuiltinHandler {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func {
+		panic(fmt.Sprintf("ucl: expected a func but was %T", fn))
+	}
+	ft := fv.Type()
+
+	sig := funcSignature{}
+	params := make([]reflect.Type, ft.NumIn())
+	for i := range params {
+		params[i] = ft.In(i)
+	}
+
+	if len(params) > 0 && params[0] == contextType {
+		sig.hasCtx = true
+		params = params[1:]
+	}
+	if ft.IsVariadic() {
+		sig.variadic = params[len(params)-1].Elem()
+		params = params[:len(params)-1]
+	}
+	if len(params) > 0 && isSwitchStruct(params[len(params)-1]) {
+		sig.switches = params[len(params)-1]
+		params = params[:len(params)-1]
+	}
+	sig.positional = params
+
+	switch ft.NumOut() {
+	case 0:
+	case 1:
+		sig.returnsErr = ft.Out(0) == errorType
+		sig.returnsVal = !sig.returnsErr
+	case 2:
+		if ft.Out(1) != errorType {
+			panic(fmt.Sprintf("ucl: expected second return value of %v to be an error", ft))
+		}
+		sig.returnsVal, sig.returnsErr = true, true
+	default:
+		panic(fmt.Sprintf("ucl: too many return values from %v", ft))
+	}
+
+	return func(ctx context.Context, args CallArgs) (any, error) {
+		return sig.call(ctx, fv, args)
+	}
+}
+
+type funcSignature struct {
+	hasCtx     bool
+	positional []reflect.Type
+	switches   reflect.Type
+	variadic   reflect.Type
+	returnsVal bool
+	returnsErr bool
+}
+
+func (sig funcSignature) call(ctx context.Context, fv reflect.Value, args CallArgs) (any, error) {
+	if n := args.NArgs(); sig.variadic == nil && n != len(sig.positional) {
+		return nil, fmt.Errorf("expected %d args but was %d", len(sig.positional), n)
+	} else if n < len(sig.positional) {
+		return nil, fmt.Errorf("expected at least %d args but was %d", len(sig.positional), n)
+	}
+
+	in := make([]reflect.Value, 0, fv.Type().NumIn()+args.NArgs())
+	if sig.hasCtx {
+		in = append(in, reflect.ValueOf(ctx))
+	}
+
+	for _, pt := range sig.positional {
+		pv := reflect.New(pt)
+		if err := args.Bind(pv.Interface()); err != nil {
+			return nil, err
+		}
+		in = append(in, pv.Elem())
+	}
+
+	known := make(map[string]bool)
+	if sig.switches != nil {
+		sv := reflect.New(sig.switches).Elem()
+		for _, f := range reflect.VisibleFields(sig.switches) {
+			name, ok := switchName(f)
+			if !ok || !f.IsExported() {
+				continue
+			}
+			if err := args.BindSwitch(name, sv.FieldByIndex(f.Index).Addr().Interface()); err != nil {
+				return nil, err
+			}
+			known[name] = true
+		}
+		in = append(in, sv)
+	}
+	for _, name := range args.SwitchNames() {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown switch -%v", name)
+		}
+	}
+
+	if sig.variadic != nil {
+		for args.NArgs() > 0 {
+			pv := reflect.New(sig.variadic)
+			if err := args.Bind(pv.Interface()); err != nil {
+				return nil, err
+			}
+			in = append(in, pv.Elem())
+		}
+	}
+
+	out := fv.Call(in)
+	if sig.returnsErr {
+		if errVal := out[len(out)-1]; !errVal.IsNil() {
+			return nil, errVal.Interface().(error)
+		}
+	}
+	if sig.returnsVal {
+		return out[0].Interface(), nil
+	}
+	return nil, nil
+}
+
+func isSwitchStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for _, f := range reflect.VisibleFields(t) {
+		if _, ok := switchName(f); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func switchName(f reflect.StructField) (string, bool) {
+	tag, ok := f.Tag.Lookup("ucl")
+	if !ok || !strings.HasPrefix(tag, "-") {
+		return "", false
+	}
+	return tag[1:], true
+}
@@ -0,0 +1,109 @@
+package ucl_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"ucl.lmika.dev/ucl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInst_SetFunc(t *testing.T) {
+	type image struct {
+		W, H    int
+		Quality int
+	}
+
+	tests := []struct {
+		descr   string
+		eval    string
+		want    any
+		wantErr string
+	}{
+		{descr: "no args", eval: `hello`, want: "hello"},
+		{descr: "strings", eval: `join "a" "b"`, want: "a:b"},
+		{descr: "context and ints", eval: `add3 1 2 3`, want: 6},
+		{descr: "variadic", eval: `sum 1 2 3 4`, want: 10},
+		{descr: "variadic with none", eval: `sum`, want: 0},
+		{descr: "error only", eval: `fail "boom"`, wantErr: "boom"},
+		{descr: "no return", eval: `nothing 1`, want: nil},
+		{descr: "proxies and switches", eval: `resize (newImage) 10 20 -quality 80`, want: &image{W: 10, H: 20, Quality: 80}},
+		{descr: "switches not set", eval: `resize (newImage) 10 20`, want: &image{W: 10, H: 20}},
+		{descr: "bool switch", eval: `shout "hello" -loud`, want: "HELLO"},
+		{descr: "bool switch not set", eval: `shout "hello"`, want: "hello"},
+		{descr: "pipe", eval: `newImage | resize 5 6`, want: &image{W: 5, H: 6}},
+		{descr: "too few args", eval: `join "a"`, wantErr: "expected 2 args but was 1"},
+		{descr: "too many args", eval: `join "a" "b" "c"`, wantErr: "expected 2 args but was 3"},
+		{descr: "too few variadic args", eval: `prefix`, wantErr: "expected at least 1 args but was 0"},
+		{descr: "bad arg type", eval: `add3 1 "two" 3`, wantErr: "arg 1: expected int but was string"},
+		{descr: "bad switch type", eval: `resize (newImage) 10 20 -quality "high"`, wantErr: "switch -quality: expected int but was string"},
+		{descr: "switch without arg", eval: `resize (newImage) 10 20 -quality`, wantErr: "switch -quality: expected 1 arg but was 0"},
+		{descr: "switch with too many args", eval: `resize (newImage) 10 20 -quality 80 90`, wantErr: "switch -quality: expected 1 arg but was 2"},
+		{descr: "unknown switch", eval: `resize (newImage) 10 20 -size 80`, wantErr: "unknown switch -size"},
+		{descr: "unknown switch without switches", eval: `join "a" "b" -loud`, wantErr: "unknown switch -loud"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			inst := ucl.New()
+			inst.SetFunc("hello", func() string { return "hello" })
+			inst.SetFunc("join", func(a, b string) string { return a + ":" + b })
+			inst.SetFunc("add3", func(ctx context.Context, a, b, c int) (int, error) { return a + b + c, nil })
+			inst.SetFunc("sum", func(xs ...int) int {
+				n := 0
+				for _, x := range xs {
+					n += x
+				}
+				return n
+			})
+			inst.SetFunc("prefix", func(p string, xs ...string) string { return p + strings.Join(xs, "") })
+			inst.SetFunc("fail", func(msg string) error { return errors.New(msg) })
+			inst.SetFunc("nothing", func(x int) {})
+			inst.SetFunc("newImage", func() *image { return &image{} })
+			inst.SetFunc("resize", func(ctx context.Context, img *image, w, h int, opts struct {
+				Quality int `ucl:"-quality"`
+			}) (*image, error) {
+				return &image{W: w, H: h, Quality: opts.Quality}, nil
+			})
+			inst.SetFunc("shout", func(s string, opts struct {
+				Loud bool `ucl:"-loud"`
+			}) string {
+				if opts.Loud {
+					return strings.ToUpper(s)
+				}
+				return s
+			})
+
+			res, err := inst.Eval(context.Background(), tt.eval)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+		})
+	}
+
+	t.Run("panics on non-funcs", func(t *testing.T) {
+		inst := ucl.New()
+		assert.Panics(t, func() { inst.SetFunc("bad", "not a func") })
+		assert.Panics(t, func() { inst.SetFunc("bad", func() (int, int) { return 1, 2 }) })
+	})
+
+	t.Run("usable in modules", func(t *testing.T) {
+		inst := ucl.New(ucl.WithModule(ucl.Module{
+			Name: "fmt",
+			Builtins: map[string]ucl.BuiltinHandler{
+				"sprint": ucl.Func(func(xs ...any) string { return fmt.Sprint(xs...) }),
+			},
+		}))
+
+		res, err := inst.Eval(context.Background(), `fmt:sprint "a" 1 "b"`)
+		assert.NoError(t, err)
+		assert.Equal(t, "a1b", res)
+	})
+}
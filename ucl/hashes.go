@@ -0,0 +1,293 @@
+package ucl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// hashArg returns argument i as a hashable.
+func (ia invocationArgs) hashArg(i int) (hashable, error) {
+	if len(ia.args) <= i {
+		return nil, fmt.Errorf("expected at least %d args", i+1)
+	}
+
+	h, ok := ia.args[i].(hashable)
+	if !ok {
+		return nil, errors.New("expected hashable")
+	}
+	return h, nil
+}
+
+// keyArgs returns the arguments from i onwards as a list of keys. Arguments which are lists
+// contribute each of their elements.
+func (ia invocationArgs) keyArgs(i int) []string {
+	var keys []string
+	for _, a := range ia.args[min(i, len(ia.args)):] {
+		switch t := a.(type) {
+		case nil:
+		case listable:
+			for j := 0; j < t.Len(); j++ {
+				if e := t.Index(j); e != nil {
+					keys = append(keys, e.String())
+				}
+			}
+		default:
+			keys = append(keys, t.String())
+		}
+	}
+	return keys
+}
+
+// copyHash returns a new hash with the keys and values of h.
+func copyHash(h hashable) *hashObject {
+	res := newHashObject(h.Len())
+	_ = h.Each(func(k string, v object) error {
+		res.set(k, v)
+		return nil
+	})
+	return res
+}
+
+func valuesBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	h, err := args.hashArg(0)
+	if err != nil {
+		return nil, err
+	}
+
+	vals := &listObject{elems: make([]object, 0, h.Len())}
+	if err := h.Each(func(_ string, v object) error {
+		vals.Append(v)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+func entriesBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	h, err := args.hashArg(0)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := &listObject{elems: make([]object, 0, h.Len())}
+	if err := h.Each(func(k string, v object) error {
+		entries.Append(&listObject{elems: []object{strObject(k), v}})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func fromEntriesBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	res := newHashObject(0)
+	for i := 0; i < l.Len(); i++ {
+		entry, ok := l.Index(i).(listable)
+		if !ok || entry.Len() != 2 || entry.Index(0) == nil {
+			return nil, fmt.Errorf("element %v is not a [KEY VALUE] pair", i)
+		}
+		res.set(entry.Index(0).String(), entry.Index(1))
+	}
+	return res, nil
+}
+
+func mergeBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	deep := args.hasSwitch("deep")
+
+	res := newHashObject(0)
+	for i := range args.args {
+		h, err := args.hashArg(i)
+		if err != nil {
+			return nil, err
+		}
+		mergeInto(res, h, deep)
+	}
+	return res, nil
+}
+
+// mergeInto sets the keys of h on dest. If deep is set, hashes present in both dest and h
+// are themselves merged.
+func mergeInto(dest *hashObject, h hashable, deep bool) {
+	_ = h.Each(func(k string, v object) error {
+		if deep {
+			dh, dok := dest.Value(k).(hashable)
+			vh, vok := v.(hashable)
+			if dok && vok {
+				merged := copyHash(dh)
+				mergeInto(merged, vh, true)
+				dest.set(k, merged)
+				return nil
+			}
+		}
+		dest.set(k, v)
+		return nil
+	})
+}
+
+func putBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	h, err := args.hashArg(0)
+	if err != nil {
+		return nil, err
+	} else if len(args.args)%2 != 1 {
+		return nil, errors.New("expected KEY VALUE pairs")
+	}
+
+	res := copyHash(h)
+	for i := 1; i < len(args.args); i += 2 {
+		k, err := args.stringArg(i)
+		if err != nil {
+			return nil, err
+		}
+		res.set(k, args.args[i+1])
+	}
+	return res, nil
+}
+
+func delBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	h, err := args.hashArg(0)
+	if err != nil {
+		return nil, err
+	}
+
+	res := copyHash(h)
+	for _, k := range args.keyArgs(1) {
+		res.del(k)
+	}
+	return res, nil
+}
+
+func hasKeyBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(2); err != nil {
+		return nil, err
+	}
+
+	h, err := args.hashArg(0)
+	if err != nil {
+		return nil, err
+	}
+	key, err := args.stringArg(1)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	if err := h.Each(func(k string, _ object) error {
+		found = found || k == key
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return boolObject(found), nil
+}
+
+func pickBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	h, err := args.hashArg(0)
+	if err != nil {
+		return nil, err
+	}
+
+	all := copyHash(h)
+	res := newHashObject(0)
+	for _, k := range args.keyArgs(1) {
+		if all.has(k) {
+			res.set(k, all.Value(k))
+		}
+	}
+	return res, nil
+}
+
+func omitBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	return delBuiltin(ctx, args)
+}
+
+func mapValuesBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(2); err != nil {
+		return nil, err
+	}
+
+	h, err := args.hashArg(0)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := args.invokableArg(1)
+	if err != nil {
+		return nil, err
+	}
+
+	res := newHashObject(h.Len())
+	if err := h.Each(func(k string, v object) error {
+		m, err := inv.invoke(ctx, args.fork([]object{v, strObject(k)}))
+		if err != nil {
+			return err
+		}
+		res.set(k, m)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// setKeyBuiltin sets the keys of a hash in place, and returns the hash.
+func setKeyBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(3); err != nil {
+		return nil, err
+	} else if len(args.args)%2 != 1 {
+		return nil, errors.New("expected KEY VALUE pairs")
+	}
+
+	for i := 1; i < len(args.args); i += 2 {
+		k, err := args.stringArg(i)
+		if err != nil {
+			return nil, err
+		}
+
+		v := args.args[i+1]
+		switch t := args.args[0].(type) {
+		case *hashObject:
+			t.set(k, v)
+		case mapProxyObject:
+			err = t.setValue(args, k, v)
+		case structProxyObject:
+			err = t.setValue(args, k, v)
+		default:
+			return nil, fmt.Errorf("cannot set keys of %v", typeName(args.args[0]))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return args.args[0], nil
+}
+
+// delKeyBuiltin removes the keys from a hash in place, and returns the hash.
+func delKeyBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(1); err != nil {
+		return nil, err
+	}
+
+	switch t := args.args[0].(type) {
+	case *hashObject:
+		for _, k := range args.keyArgs(1) {
+			t.del(k)
+		}
+	case mapProxyObject:
+		if t.v.IsNil() {
+			break
+		}
+		for _, k := range args.keyArgs(1) {
+			t.v.SetMapIndex(reflect.ValueOf(k).Convert(t.v.Type().Key()), reflect.Value{})
+		}
+	default:
+		return nil, fmt.Errorf("cannot delete keys of %v", typeName(args.args[0]))
+	}
+	return args.args[0], nil
+}
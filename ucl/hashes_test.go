@@ -0,0 +1,88 @@
+package ucl
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltins_Hashes(t *testing.T) {
+	type config struct {
+		Host string
+		Port int
+	}
+
+	tests := []struct {
+		desc    string
+		expr    string
+		want    any
+		wantErr bool
+	}{
+		{desc: "keys ordered", expr: `keys [c:1 a:2 b:3]`, want: []any{"c", "a", "b"}},
+		{desc: "foreach ordered", expr: `set s "" ; foreach [c:1 a:2 b:3] { |k v| set s (cat $s $k $v) } ; $s`, want: "c1a2b3"},
+		{desc: "values", expr: `values [c:1 a:2 b:3]`, want: []any{1, 2, 3}},
+		{desc: "values map proxy", expr: `goMap | values`, want: []any{"x", "y"}},
+		{desc: "entries", expr: `entries [b:2 a:1]`, want: []any{[]any{"b", 2}, []any{"a", 1}}},
+		{desc: "entries struct proxy", expr: `entries (goStruct)`, want: []any{[]any{"Host", "localhost"}, []any{"Port", 8080}}},
+		{desc: "fromEntries", expr: `fromEntries [["a" 1] ["b" 2]]`, want: map[string]any{"a": 1, "b": 2}},
+		{desc: "fromEntries round trip", expr: `entries [a:1 b:2] | map { |e| [(index $e 0 | toUpper) (index $e 1)] } | fromEntries`, want: map[string]any{"A": 1, "B": 2}},
+		{desc: "fromEntries bad pair", expr: `fromEntries [["a" 1 2]]`, wantErr: true},
+
+		{desc: "merge", expr: `merge [a:1 b:2] [b:3 c:4]`, want: map[string]any{"a": 1, "b": 3, "c": 4}},
+		{desc: "merge shallow", expr: `merge [a:[x:1 y:2]] [a:[y:3]]`, want: map[string]any{"a": map[string]any{"y": 3}}},
+		{desc: "merge deep", expr: `merge [a:[x:1 y:2] b:1] [a:[y:3]] -deep`, want: map[string]any{"a": map[string]any{"x": 1, "y": 3}, "b": 1}},
+		{desc: "merge proxy", expr: `merge (goStruct) [Port:9000]`, want: map[string]any{"Host": "localhost", "Port": 9000}},
+		{desc: "merge non-hash", expr: `merge [a:1] [1 2]`, wantErr: true},
+
+		{desc: "put", expr: `put [a:1] "b" 2 "c" 3`, want: map[string]any{"a": 1, "b": 2, "c": 3}},
+		{desc: "put leaves original", expr: `set h [a:1] ; put $h "a" 2 ; $h`, want: map[string]any{"a": 1}},
+		{desc: "put missing value", expr: `put [a:1] "b"`, wantErr: true},
+		{desc: "del", expr: `del [a:1 b:2 c:3] "a" "c"`, want: map[string]any{"b": 2}},
+		{desc: "del leaves original", expr: `set h [a:1 b:2] ; del $h "a" ; $h`, want: map[string]any{"a": 1, "b": 2}},
+		{desc: "hasKey", expr: `hasKey [a:1] "a"`, want: true},
+		{desc: "hasKey nil value", expr: `hasKey [a:()] "a"`, want: true},
+		{desc: "hasKey missing", expr: `hasKey [a:1] "b"`, want: false},
+		{desc: "hasKey missing key", expr: `hasKey [a:1]`, wantErr: true},
+		{desc: "pick", expr: `pick [a:1 b:2 c:3] "a" "c" "d"`, want: map[string]any{"a": 1, "c": 3}},
+		{desc: "pick list", expr: `pick [a:1 b:2 c:3] ["b"]`, want: map[string]any{"b": 2}},
+		{desc: "omit", expr: `omit [a:1 b:2 c:3] ["a" "b"]`, want: map[string]any{"c": 3}},
+		{desc: "mapValues", expr: `mapValues [a:1 b:2] { |v| add $v 10 }`, want: map[string]any{"a": 11, "b": 12}},
+		{desc: "mapValues key", expr: `mapValues [a:1 b:2] { |v k| cat $k $v }`, want: map[string]any{"a": "a1", "b": "b2"}},
+		{desc: "mapValues missing block", expr: `mapValues [a:1]`, wantErr: true},
+		{desc: "set-key", expr: `set h [a:1] ; set-key $h "b" 2 "a" 3 ; $h`, want: map[string]any{"a": 3, "b": 2}},
+		{desc: "set-key pipe", expr: `[:] | set-key "a" 1 | set-key "b" 2 | keys`, want: []any{"a", "b"}},
+		{desc: "set-key seen through other references", expr: `set h [a:1] ; set g $h ; set-key $h "b" 2 ; $g`, want: map[string]any{"a": 1, "b": 2}},
+		{desc: "set-key map proxy", expr: `set m (goMap) ; set-key $m "c" "z" ; $m`, want: map[string]string{"a": "x", "b": "y", "c": "z"}},
+		{desc: "set-key missing value", expr: `set-key [a:1] "b"`, wantErr: true},
+		{desc: "set-key non-hash", expr: `set-key [1 2] "a" 1`, wantErr: true},
+		{desc: "del-key", expr: `set h [a:1 b:2 c:3] ; del-key $h "a" ["c"] ; $h`, want: map[string]any{"b": 2}},
+		{desc: "del-key map proxy", expr: `set m (goMap) ; del-key $m "a" ; $m`, want: map[string]string{"b": "y"}},
+		{desc: "del-key struct proxy", expr: `del-key (goStruct) "Host"`, wantErr: true},
+		{desc: "mapValues pipe", expr: `goMap | mapValues { |v| toUpper $v }`, want: map[string]any{"a": "X", "b": "Y"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ctx := context.Background()
+			outW := bytes.NewBuffer(nil)
+
+			inst := New(WithOut(outW), WithTestBuiltin())
+			inst.SetBuiltin("goMap", func(ctx context.Context, args CallArgs) (any, error) {
+				return map[string]string{"b": "y", "a": "x"}, nil
+			})
+			inst.SetBuiltin("goStruct", func(ctx context.Context, args CallArgs) (any, error) {
+				return config{Host: "localhost", Port: 8080}, nil
+			})
+
+			res, err := inst.Eval(ctx, tt.expr)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+}
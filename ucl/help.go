@@ -0,0 +1,214 @@
+package ucl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Doc describes a command for the help builtin and for tooling.
+type Doc struct {
+	// Description describes what the command does. The first line is used as a summary.
+	Description string
+
+	// Args are the names of the positional arguments of the command.
+	Args []string
+
+	// Switches are the switches accepted by the command.
+	Switches []SwitchDoc
+}
+
+// SwitchDoc describes a switch accepted by a command.
+type SwitchDoc struct {
+	// Name is the name of the switch, without the leading dash.
+	Name string
+
+	// Arg is the name of the switch argument, or empty if the switch takes no argument.
+	Arg string
+
+	Description string
+}
+
+func (d Doc) summary() string {
+	s, _, _ := strings.Cut(d.Description, "\n")
+	return s
+}
+
+// SetDoc sets the documentation of the command with the given name.
+func (inst *Inst) SetDoc(name string, doc Doc) {
+	if inst.docs == nil {
+		inst.docs = make(map[string]Doc)
+	}
+	inst.docs[name] = doc
+}
+
+// Describe returns the documentation of the command with the given name. Procs are described
+// by the comment appearing directly above their definition. Returns false if no such
+// command exists.
+func (inst *Inst) Describe(name string) (Doc, bool) {
+	switch cmd := inst.rootEC.lookupInvokable(name).(type) {
+	case procObject:
+		return Doc{Description: cmd.doc, Args: cmd.block.Names}, true
+	case nil:
+		if inst.rootEC.lookupMacro(name) == nil {
+			return Doc{}, false
+		}
+	}
+
+	if doc, ok := inst.docs[name]; ok {
+		return doc, true
+	}
+	return coreDocs[name], true
+}
+
+func (inst *Inst) commandNames() []string {
+	names := make([]string, 0, len(inst.rootEC.commands)+len(inst.rootEC.macros))
+	for name := range inst.rootEC.commands {
+		names = append(names, name)
+	}
+	for name := range inst.rootEC.macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func helpBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if len(args.args) == 0 {
+		tw := tabwriter.NewWriter(args.inst.Out(), 0, 0, 2, ' ', 0)
+		for _, name := range args.inst.commandNames() {
+			doc, _ := args.inst.Describe(name)
+			if _, err := fmt.Fprintf(tw, "%v\t%v\n", name, doc.summary()); err != nil {
+				return nil, err
+			}
+		}
+		return nil, tw.Flush()
+	}
+
+	name, err := args.stringArg(0)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, ok := args.inst.Describe(name)
+	if !ok {
+		return nil, errors.New("unknown command: " + name)
+	}
+	return nil, writeDoc(args.inst.Out(), name, doc)
+}
+
+func writeDoc(w io.Writer, name string, doc Doc) error {
+	usage := append([]string{name}, doc.Args...)
+	if len(doc.Switches) > 0 {
+		usage = append(usage, "[SWITCHES]")
+	}
+	if _, err := fmt.Fprintf(w, "usage: %v\n", strings.Join(usage, " ")); err != nil {
+		return err
+	}
+
+	if doc.Description != "" {
+		if _, err := fmt.Fprintf(w, "\n%v\n", doc.Description); err != nil {
+			return err
+		}
+	}
+
+	if len(doc.Switches) > 0 {
+		if _, err := fmt.Fprintln(w, "\nswitches:"); err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, sw := range doc.Switches {
+			if _, err := fmt.Fprintf(tw, "  -%v\t%v\t%v\n", sw.Name, sw.Arg, sw.Description); err != nil {
+				return err
+			}
+		}
+		return tw.Flush()
+	}
+	return nil
+}
+
+var coreDocs = map[string]Doc{
+	"echo":    {Description: "Writes the arguments to the output, followed by a newline.", Args: []string{"ARGS..."}},
+	"set":     {Description: "Sets the variable NAME to VALUE, defining it in the current scope if it does not exist.", Args: []string{"NAME", "VALUE"}},
+	"toUpper": {Description: "Returns STR converted to upper case.", Args: []string{"STR"}},
+	"len":     {Description: "Returns the length of a string, list or hash.", Args: []string{"VALUE"}},
+	"keys":    {Description: "Returns the keys of a hash as a list, in the order they were added.", Args: []string{"HASH"}},
+	"index":   {Description: "Returns the element of a list or hash, following each index in turn.", Args: []string{"VALUE", "INDEX..."}},
+	"call":    {Description: "Invokes a block or proc with the remaining arguments.", Args: []string{"BLOCK", "ARGS..."}},
+	"set-at":  {Description: "Sets the element of a list, hash or Go value at KEY to VALUE.", Args: []string{"TARGET", "KEY", "VALUE"}},
+	"append":  {Description: "Returns a list with the values appended to the end.\nGo slices reachable through a pointer are modified in place.", Args: []string{"LIST", "VALUES..."}},
+	"map":     {Description: "Returns a list with each element of LIST transformed by BLOCK.", Args: []string{"LIST", "BLOCK"}},
+	"filter":  {Description: "Returns the elements of a list or hash for which BLOCK returns a truthy value.", Args: []string{"LIST", "BLOCK"}},
+	"head":    {Description: "Returns the first element of a list.", Args: []string{"LIST"}},
+	"reduce":  {Description: "Reduces a list to a single value by calling BLOCK with each element and the accumulator.", Args: []string{"LIST", "[INITIAL]", "BLOCK"}},
+	"sort": {
+		Description: "Returns the elements of LIST sorted in ascending order, or by the result of KEY if given.",
+		Args:        []string{"LIST", "[KEY]"},
+		Switches:    []SwitchDoc{{Name: "desc", Description: "Sort in descending order"}},
+	},
+	"uniq":    {Description: "Returns the elements of LIST with duplicates removed, or those with duplicate results of KEY.", Args: []string{"LIST", "[KEY]"}},
+	"reverse": {Description: "Returns the elements of LIST in reverse order.", Args: []string{"LIST"}},
+	"take":    {Description: "Returns the first N elements of LIST.", Args: []string{"LIST", "N"}},
+	"skip":    {Description: "Returns the elements of LIST after the first N.", Args: []string{"LIST", "N"}},
+	"tail":    {Description: "Returns the last N elements of LIST.", Args: []string{"LIST", "N"}},
+	"slice":   {Description: "Returns the elements of LIST from START up to, but not including, END.\nNegative positions are relative to the end of LIST.", Args: []string{"LIST", "START", "[END]"}},
+	"flatten": {
+		Description: "Returns LIST with the elements of nested lists included in place of the lists.",
+		Args:        []string{"LIST"},
+		Switches:    []SwitchDoc{{Name: "deep", Description: "Flatten lists at every level, rather than just the first"}},
+	},
+	"zip":         {Description: "Returns a list of lists, made up of the elements of each LIST at the same position.", Args: []string{"LISTS..."}},
+	"enumerate":   {Description: "Returns a list of [INDEX ELEMENT] pairs for each element of LIST.", Args: []string{"LIST"}},
+	"groupBy":     {Description: "Returns a hash of the results of KEY to the list of elements with that result.", Args: []string{"LIST", "KEY"}},
+	"partition":   {Description: "Returns a list of two lists: the elements for which BLOCK is truthy, and the rest.", Args: []string{"LIST", "BLOCK"}},
+	"chunk":       {Description: "Returns LIST split into lists of SIZE elements. The last list may be shorter.", Args: []string{"LIST", "SIZE"}},
+	"any":         {Description: "Returns true if BLOCK is truthy for any element of LIST, or any element is truthy.", Args: []string{"LIST", "[BLOCK]"}},
+	"all":         {Description: "Returns true if BLOCK is truthy for every element of LIST, or every element is truthy.", Args: []string{"LIST", "[BLOCK]"}},
+	"find":        {Description: "Returns the first element of LIST for which BLOCK is truthy, or nil if there is none.", Args: []string{"LIST", "BLOCK"}},
+	"sum":         {Description: "Returns the sum of the numbers of LIST.", Args: []string{"LIST"}},
+	"min":         {Description: "Returns the smallest element of LIST, compared by the result of KEY if given.", Args: []string{"LIST", "[KEY]"}},
+	"max":         {Description: "Returns the largest element of LIST, compared by the result of KEY if given.", Args: []string{"LIST", "[KEY]"}},
+	"range":       {Description: "Returns a list of the ints from START, which defaults to 0, up to but not including END.", Args: []string{"[START]", "END", "[STEP]"}},
+	"concat":      {Description: "Returns a list of the elements of each LIST in turn.", Args: []string{"LISTS..."}},
+	"push":        {Description: "Adds the values to the end of LIST in place, and returns LIST.", Args: []string{"LIST", "VALUES..."}},
+	"pop":         {Description: "Removes and returns the last element of LIST, or nil if LIST is empty.", Args: []string{"LIST"}},
+	"shift":       {Description: "Removes and returns the first element of LIST, or nil if LIST is empty.", Args: []string{"LIST"}},
+	"unshift":     {Description: "Adds the values to the start of LIST in place, and returns LIST.", Args: []string{"LIST", "VALUES..."}},
+	"values":      {Description: "Returns the values of a hash as a list, in the order the keys were added.", Args: []string{"HASH"}},
+	"entries":     {Description: "Returns a list of [KEY VALUE] pairs for each key of a hash, in the order the keys were added.", Args: []string{"HASH"}},
+	"fromEntries": {Description: "Returns a hash built from a list of [KEY VALUE] pairs.", Args: []string{"LIST"}},
+	"merge": {
+		Description: "Returns a new hash with the keys of each HASH in turn, with later values replacing earlier ones.",
+		Args:        []string{"HASHES..."},
+		Switches:    []SwitchDoc{{Name: "deep", Description: "Merge values which are hashes in both, rather than replacing them"}},
+	},
+	"put":       {Description: "Returns a copy of HASH with each KEY set to VALUE.", Args: []string{"HASH", "KEY", "VALUE", "[KEY VALUE]..."}},
+	"del":       {Description: "Returns a copy of HASH without the keys.", Args: []string{"HASH", "KEYS..."}},
+	"hasKey":    {Description: "Returns true if HASH has the key, even if the value is nil.", Args: []string{"HASH", "KEY"}},
+	"pick":      {Description: "Returns a hash of only the keys of HASH which are listed. Keys can be given as lists.", Args: []string{"HASH", "KEYS..."}},
+	"omit":      {Description: "Returns a hash of the keys of HASH which are not listed. Keys can be given as lists.", Args: []string{"HASH", "KEYS..."}},
+	"mapValues": {Description: "Returns a hash with each value of HASH transformed by BLOCK, which is called with the value and key.", Args: []string{"HASH", "BLOCK"}},
+	"set-key":   {Description: "Sets each KEY of HASH to VALUE in place, and returns HASH.", Args: []string{"HASH", "KEY", "VALUE", "[KEY VALUE]..."}},
+	"del-key":   {Description: "Removes the keys from HASH in place, and returns HASH.", Args: []string{"HASH", "KEYS..."}},
+	"true":      {Description: "Returns true."},
+	"false":     {Description: "Returns false."},
+	"eq":        {Description: "Returns true if the two values are equal. Ints and floats are compared by value.", Args: []string{"LEFT", "RIGHT"}},
+	"add":       {Description: "Returns the sum of the arguments as an integer.", Args: []string{"NUMS..."}},
+	"cat":       {Description: "Concatenates the arguments into a single string.", Args: []string{"ARGS..."}},
+	"break":     {Description: "Stops the current loop, optionally returning VALUE.", Args: []string{"[VALUE]"}},
+	"continue":  {Description: "Skips to the next iteration of the current loop."},
+	"return":    {Description: "Returns from the current proc, optionally with VALUE.", Args: []string{"[VALUE]"}},
+	"exit":      {Description: "Stops evaluation of the script with an exit code, which defaults to 0.", Args: []string{"[CODE]"}},
+	"import":    {Description: "Evaluates a script module and makes its procs available as NAME:proc.\nWithin a script module, PATH is relative to the directory of the module.", Args: []string{"PATH", "[as NAME]"}},
+	"repr":      {Description: "Returns VALUE as UCL source, which evaluates to an equivalent value.", Args: []string{"VALUE"}},
+	"inspect":   {Description: "Writes the type and source form of VALUE to the output, and returns VALUE.", Args: []string{"VALUE"}},
+	"help":      {Description: "Lists the available commands, or describes a single command.", Args: []string{"[COMMAND]"}},
+	"if":        {Description: "Evaluates the block of the first truthy guard.", Args: []string{"GUARD", "BLOCK", "[elif GUARD BLOCK]...", "[else BLOCK]"}},
+	"foreach":   {Description: "Evaluates BLOCK for each element of a list, or each key and value of a hash.", Args: []string{"LIST", "BLOCK"}},
+	"proc":      {Description: "Defines a proc, which is named if NAME is given.", Args: []string{"[NAME]", "BLOCK"}},
+}
@@ -0,0 +1,141 @@
+package ucl_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"ucl.lmika.dev/ucl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInst_Describe(t *testing.T) {
+	ctx := context.Background()
+
+	inst := ucl.New(ucl.WithModule(ucl.Module{
+		Name: "img",
+		Builtins: map[string]ucl.BuiltinHandler{
+			"resize": ucl.Func(func(w, h int) int { return w * h }),
+		},
+		Docs: map[string]ucl.Doc{
+			"resize": {
+				Description: "Resizes an image.",
+				Args:        []string{"W", "H"},
+				Switches:    []ucl.SwitchDoc{{Name: "quality", Arg: "Q", Description: "The output quality"}},
+			},
+		},
+	}))
+	inst.SetBuiltin("undocumented", func(ctx context.Context, args ucl.CallArgs) (any, error) { return nil, nil })
+	inst.SetBuiltin("documented", func(ctx context.Context, args ucl.CallArgs) (any, error) { return nil, nil })
+	inst.SetDoc("documented", ucl.Doc{Description: "Has docs."})
+
+	_, err := inst.Eval(ctx, `
+		# Greets someone.
+		#
+		# Returns the greeting.
+		proc greet { |who greeting|
+			cat $greeting ", " $who
+		}
+
+		# Not a doc comment
+
+		proc noDocs { }
+		proc sameLine { } # Not a doc comment either
+	`)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		want    ucl.Doc
+		wantErr bool
+	}{
+		{name: "echo", want: ucl.Doc{Description: "Writes the arguments to the output, followed by a newline.", Args: []string{"ARGS..."}}},
+		{name: "if", want: ucl.Doc{Description: "Evaluates the block of the first truthy guard.", Args: []string{"GUARD", "BLOCK", "[elif GUARD BLOCK]...", "[else BLOCK]"}}},
+		{name: "img:resize", want: ucl.Doc{
+			Description: "Resizes an image.",
+			Args:        []string{"W", "H"},
+			Switches:    []ucl.SwitchDoc{{Name: "quality", Arg: "Q", Description: "The output quality"}},
+		}},
+		{name: "documented", want: ucl.Doc{Description: "Has docs."}},
+		{name: "undocumented", want: ucl.Doc{}},
+		{name: "greet", want: ucl.Doc{Description: "Greets someone.\n\nReturns the greeting.", Args: []string{"who", "greeting"}}},
+		{name: "noDocs", want: ucl.Doc{}},
+		{name: "sameLine", want: ucl.Doc{}},
+		{name: "missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, ok := inst.Describe(tt.name)
+			assert.Equal(t, !tt.wantErr, ok)
+			assert.Equal(t, tt.want, doc)
+		})
+	}
+}
+
+func TestBuiltins_Help(t *testing.T) {
+	tests := []struct {
+		desc    string
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{desc: "help for builtin", expr: `help echo`, want: "usage: echo ARGS...\n\nWrites the arguments to the output, followed by a newline.\n"},
+		{desc: "help for proc", expr: `
+			# Says hello
+			proc hello { |who| echo "Hello " $who }
+			help hello
+		`, want: "usage: hello who\n\nSays hello\n"},
+		{desc: "help with switches", expr: `help greet`, want: strings.Join([]string{
+			"usage: greet NAME [SWITCHES]",
+			"",
+			"Greets someone.",
+			"",
+			"switches:",
+			"  -loud         Shout the greeting",
+			"  -greeting  G  Use a different greeting",
+			"",
+		}, "\n")},
+		{desc: "help for missing command", expr: `help missing`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ctx := context.Background()
+			outW := bytes.NewBuffer(nil)
+
+			inst := ucl.New(ucl.WithOut(outW))
+			inst.SetBuiltin("greet", func(ctx context.Context, args ucl.CallArgs) (any, error) { return nil, nil })
+			inst.SetDoc("greet", ucl.Doc{
+				Description: "Greets someone.",
+				Args:        []string{"NAME"},
+				Switches: []ucl.SwitchDoc{
+					{Name: "loud", Description: "Shout the greeting"},
+					{Name: "greeting", Arg: "G", Description: "Use a different greeting"},
+				},
+			})
+
+			_, err := inst.Eval(ctx, tt.expr)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, outW.String())
+			}
+		})
+	}
+
+	t.Run("list commands", func(t *testing.T) {
+		ctx := context.Background()
+		outW := bytes.NewBuffer(nil)
+
+		inst := ucl.New(ucl.WithOut(outW))
+		_, err := inst.Eval(ctx, `help`)
+		assert.NoError(t, err)
+
+		assert.Regexp(t, `(?m)^add +Returns the sum of the arguments as an integer\.$`, outW.String())
+		assert.Regexp(t, `(?m)^foreach +Evaluates BLOCK for each element of a list, or each key and value of a hash\.$`, outW.String())
+	})
+}
@@ -4,15 +4,36 @@ import (
 	"context"
 	"errors"
 	"io"
+	"io/fs"
+	"math/rand"
 	"os"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/lmika/gopkgs/fp/maps"
 )
 
 type Inst struct {
 	out                   io.Writer
 	missingBuiltinHandler MissingBuiltinHandler
+	moduleFS              fs.FS
+	displayer             Displayer
+	orderedHashes         bool
+	clock                 func() time.Time
+	rand                  *rand.Rand
+
+	stateMu sync.Mutex
+	state   map[any]any
 
-	rootEC *evalCtx
+	rootEC        *evalCtx
+	docs          map[string]Doc
+	modules       []*instModule
+	scriptModules map[string]*scriptModule
+	importing     []string
+	initErr       error
 }
 
 type InstOption func(*Inst)
@@ -29,19 +50,6 @@ func WithMissingBuiltinHandler(handler MissingBuiltinHandler) InstOption {
 	}
 }
 
-func WithModule(module Module) InstOption {
-	return func(i *Inst) {
-		for name, builtin := range module.Builtins {
-			i.SetBuiltin(module.Name+":"+name, builtin)
-		}
-	}
-}
-
-type Module struct {
-	Name     string
-	Builtins map[string]BuiltinHandler
-}
-
 func New(opts ...InstOption) *Inst {
 	rootEC := &evalCtx{}
 	rootEC.root = rootEC
@@ -53,12 +61,55 @@ func New(opts ...InstOption) *Inst {
 	rootEC.addCmd("keys", invokableFunc(keysBuiltin))
 	rootEC.addCmd("index", invokableFunc(indexBuiltin))
 	rootEC.addCmd("call", invokableFunc(callBuiltin))
+	rootEC.addCmd("set-at", invokableFunc(setAtBuiltin))
+	rootEC.addCmd("append", invokableFunc(appendBuiltin))
 
 	rootEC.addCmd("map", invokableFunc(mapBuiltin))
 	rootEC.addCmd("filter", invokableFunc(filterBuiltin))
 	rootEC.addCmd("head", invokableFunc(firstBuiltin))
 	rootEC.addCmd("reduce", invokableFunc(reduceBuiltin))
 
+	rootEC.addCmd("sort", invokableFunc(sortBuiltin))
+	rootEC.addCmd("uniq", invokableFunc(uniqBuiltin))
+	rootEC.addCmd("reverse", invokableFunc(reverseBuiltin))
+	rootEC.addCmd("take", invokableFunc(takeBuiltin))
+	rootEC.addCmd("skip", invokableFunc(skipBuiltin))
+	rootEC.addCmd("tail", invokableFunc(tailBuiltin))
+	rootEC.addCmd("slice", invokableFunc(sliceBuiltin))
+	rootEC.addCmd("flatten", invokableFunc(flattenBuiltin))
+	rootEC.addCmd("zip", invokableFunc(zipBuiltin))
+	rootEC.addCmd("enumerate", invokableFunc(enumerateBuiltin))
+	rootEC.addCmd("groupBy", invokableFunc(groupByBuiltin))
+	rootEC.addCmd("partition", invokableFunc(partitionBuiltin))
+	rootEC.addCmd("chunk", invokableFunc(chunkBuiltin))
+	rootEC.addCmd("any", invokableFunc(anyBuiltin))
+	rootEC.addCmd("all", invokableFunc(allBuiltin))
+	rootEC.addCmd("find", invokableFunc(findBuiltin))
+	rootEC.addCmd("sum", invokableFunc(sumBuiltin))
+	rootEC.addCmd("min", extremeBuiltin(-1))
+	rootEC.addCmd("max", extremeBuiltin(1))
+	rootEC.addCmd("range", invokableFunc(rangeBuiltin))
+	rootEC.addCmd("concat", invokableFunc(concatListsBuiltin))
+	rootEC.addCmd("push", invokableFunc(pushBuiltin))
+	rootEC.addCmd("pop", invokableFunc(popBuiltin))
+	rootEC.addCmd("shift", invokableFunc(shiftBuiltin))
+	rootEC.addCmd("unshift", invokableFunc(unshiftBuiltin))
+
+	rootEC.addCmd("values", invokableFunc(valuesBuiltin))
+	rootEC.addCmd("entries", invokableFunc(entriesBuiltin))
+	rootEC.addCmd("fromEntries", invokableFunc(fromEntriesBuiltin))
+	rootEC.addCmd("merge", invokableFunc(mergeBuiltin))
+	rootEC.addCmd("put", invokableFunc(putBuiltin))
+	rootEC.addCmd("del", invokableFunc(delBuiltin))
+	rootEC.addCmd("hasKey", invokableFunc(hasKeyBuiltin))
+	rootEC.addCmd("pick", invokableFunc(pickBuiltin))
+	rootEC.addCmd("omit", invokableFunc(omitBuiltin))
+	rootEC.addCmd("mapValues", invokableFunc(mapValuesBuiltin))
+	rootEC.addCmd("set-key", invokableFunc(setKeyBuiltin))
+	rootEC.addCmd("del-key", invokableFunc(delKeyBuiltin))
+
+	rootEC.addCmd("true", invokableFunc(trueBuiltin))
+	rootEC.addCmd("false", invokableFunc(falseBuiltin))
 	rootEC.addCmd("eq", invokableFunc(eqBuiltin))
 	rootEC.addCmd("add", invokableFunc(addBuiltin))
 
@@ -66,6 +117,11 @@ func New(opts ...InstOption) *Inst {
 	rootEC.addCmd("break", invokableFunc(breakBuiltin))
 	rootEC.addCmd("continue", invokableFunc(continueBuiltin))
 	rootEC.addCmd("return", invokableFunc(returnBuiltin))
+	rootEC.addCmd("exit", invokableFunc(exitBuiltin))
+	rootEC.addCmd("import", invokableFunc(importBuiltin))
+	rootEC.addCmd("help", invokableFunc(helpBuiltin))
+	rootEC.addCmd("repr", invokableFunc(reprBuiltin))
+	rootEC.addCmd("inspect", invokableFunc(inspectBuiltin))
 
 	rootEC.addMacro("if", macroFunc(ifBuiltin))
 	rootEC.addMacro("foreach", macroFunc(foreachBuiltin))
@@ -84,6 +140,8 @@ func New(opts ...InstOption) *Inst {
 		opt(inst)
 	}
 
+	inst.initErr = inst.initModules(context.Background())
+
 	return inst
 }
 
@@ -103,15 +161,105 @@ func (inst *Inst) Eval(ctx context.Context, expr string) (any, error) {
 		return nil, err
 	}
 
-	goRes, ok := toGoValue(res)
+	return inst.rootInvocationArgs().toGoValue(res)
+}
+
+// EvalAs evaluates expr and converts the result to a value of type T. Lists can be converted
+// to slices, hashes to maps or structs, and blocks or procs to an Invokable. Struct fields are
+// matched against hash keys by name, or by the name set with a `ucl:"name"` tag.
+func EvalAs[T any](ctx context.Context, inst *Inst, expr string) (T, error) {
+	var zero T
+
+	res, err := inst.eval(ctx, expr)
+	if err != nil {
+		if errors.Is(err, ErrHalt) {
+			return zero, nil
+		}
+		return zero, err
+	}
+
+	rv, err := inst.rootInvocationArgs().toGoReflectValue(res, reflect.TypeOf(&zero).Elem())
+	if err != nil {
+		return zero, err
+	}
+
+	t, _ := rv.Interface().(T)
+	return t, nil
+}
+
+// Call invokes the command with the given name, such as a proc defined by a script, with args
+// converted to UCL values. A Switches value can be included in args to pass switches.
+func (inst *Inst) Call(ctx context.Context, name string, args ...any) (any, error) {
+	if inst.initErr != nil {
+		return nil, inst.initErr
+	}
+
+	inv := inst.rootEC.lookupInvokable(name)
+	if inv == nil {
+		return nil, errors.New("unknown command: " + name)
+	}
+
+	res, err := inst.rootInvocationArgs().invokable(inv).Invoke(ctx, args...)
+	if err != nil {
+		if errors.Is(err, ErrHalt) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// LookupProc returns the proc with the given name defined at the top-level of a script.
+func (inst *Inst) LookupProc(name string) (Invokable, bool) {
+	proc, ok := inst.rootEC.lookupInvokable(name).(procObject)
 	if !ok {
-		return nil, errors.New("result not convertable to go")
+		return Invokable{}, false
 	}
+	return inst.rootInvocationArgs().invokable(proc), true
+}
 
-	return goRes, nil
+// ProcNames returns the names of the procs defined at the top-level of a script, sorted by name.
+func (inst *Inst) ProcNames() []string {
+	var names []string
+	for name, cmd := range inst.rootEC.commands {
+		if _, ok := cmd.(procObject); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupVar returns the value of the variable with the given name defined at the top-level.
+func (inst *Inst) LookupVar(name string) (any, bool) {
+	v, ok := inst.rootEC.getVar(name)
+	if !ok {
+		return nil, false
+	}
+
+	gv, err := inst.rootInvocationArgs().toGoValue(v)
+	if err != nil {
+		return nil, false
+	}
+	return gv, true
+}
+
+// VarNames returns the names of the variables defined at the top-level, sorted by name.
+func (inst *Inst) VarNames() []string {
+	names := maps.Keys(inst.rootEC.vars)
+	sort.Strings(names)
+	return names
+}
+
+func (inst *Inst) rootInvocationArgs() invocationArgs {
+	return invocationArgs{eval: evaluator{inst: inst}, inst: inst, ec: inst.rootEC}
 }
 
 func (inst *Inst) eval(ctx context.Context, expr string) (object, error) {
+	if inst.initErr != nil {
+		return nil, inst.initErr
+	}
+
 	ast, err := parse(strings.NewReader(expr))
 	if err != nil {
 		return nil, err
@@ -3,6 +3,8 @@ package ucl_test
 import (
 	"bytes"
 	"context"
+	"errors"
+	"strings"
 	"testing"
 	"ucl.lmika.dev/ucl"
 
@@ -19,6 +21,12 @@ func TestInst_Eval(t *testing.T) {
 		{desc: "simple int 1", expr: `firstarg 123`, want: 123},
 		{desc: "simple int 2", expr: `firstarg -234`, want: -234},
 		{desc: "simple ident", expr: `firstarg a-test`, want: "a-test"},
+		{desc: "simple bool 1", expr: `eq 1 1`, want: true},
+		{desc: "simple bool 2", expr: `firstarg (eq 1 2)`, want: false},
+		{desc: "simple float 1", expr: `firstarg 1.25`, want: 1.25},
+		{desc: "simple float 2", expr: `firstarg -0.5`, want: -0.5},
+		{desc: "bools", expr: `firstarg [(true) (false) (eq 1.5 1.5)]`, want: []any{true, false, true}},
+		{desc: "int and float eq", expr: `firstarg [(eq 1 1.0) (eq 2.0 2) (eq 1 1.5) (eq 1.5 1)]`, want: []any{true, true, false, false}},
 
 		// Sub-expressions
 		{desc: "sub expression 1", expr: `firstarg (sjoin "hello")`, want: "hello"},
@@ -29,6 +37,8 @@ func TestInst_Eval(t *testing.T) {
 		{desc: "var 1", expr: `firstarg $a`, want: "alpha"},
 		{desc: "var 2", expr: `firstarg $bee`, want: "buzz"},
 		{desc: "var 3", expr: `firstarg (sjoin $bee " " $bee " " $bee)`, want: "buzz buzz buzz"},
+		{desc: "var in block", expr: `set x "outer" ; foreach [1] { firstarg $x }`, want: "outer"},
+		{desc: "var in proc", expr: `set x "outer" ; proc f { if 1 { firstarg $x } } ; f`, want: "outer"},
 
 		// Pipeline
 		{desc: "pipe 1", expr: `list "aye" "bee" "see" | joinpipe`, want: "aye,bee,see"},
@@ -91,3 +101,236 @@ func TestInst_Eval(t *testing.T) {
 		})
 	}
 }
+
+func TestInst_EvalInvokable(t *testing.T) {
+	ctx := context.Background()
+	inst := ucl.New()
+
+	res, err := inst.Eval(ctx, `proc { |x| toUpper $x }`)
+	assert.NoError(t, err)
+
+	inv, ok := res.(ucl.Invokable)
+	assert.True(t, ok)
+
+	upper, err := inv.Invoke(ctx, "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "HELLO", upper)
+}
+
+func TestEvalAs(t *testing.T) {
+	type plugin struct {
+		Name    string
+		Version int `ucl:"version"`
+		Tags    []string
+		Run     ucl.Invokable
+	}
+
+	ctx := context.Background()
+
+	t.Run("scalars", func(t *testing.T) {
+		inst := ucl.New()
+
+		s, err := ucl.EvalAs[string](ctx, inst, `cat "hello " "world"`)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello world", s)
+
+		i, err := ucl.EvalAs[int64](ctx, inst, `add 2 3`)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), i)
+
+		_, err = ucl.EvalAs[int](ctx, inst, `cat "hello"`)
+		assert.EqualError(t, err, "expected int but was string")
+	})
+
+	t.Run("collections", func(t *testing.T) {
+		inst := ucl.New()
+
+		xs, err := ucl.EvalAs[[]int](ctx, inst, `[1 2 3]`)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3}, xs)
+
+		m, err := ucl.EvalAs[map[string][]string](ctx, inst, `[a:[x y] b:[]]`)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]string{"a": {"x", "y"}, "b": {}}, m)
+	})
+
+	t.Run("plugin factories", func(t *testing.T) {
+		inst := ucl.New()
+
+		p, err := ucl.EvalAs[plugin](ctx, inst, `
+			proc makePlugin { |greeting|
+				[Name:"greeter" version:2 Tags:[a b] Run:(proc { |who| cat $greeting ", " $who })]
+			}
+			makePlugin "Hello"
+		`)
+		assert.NoError(t, err)
+		assert.Equal(t, "greeter", p.Name)
+		assert.Equal(t, 2, p.Version)
+		assert.Equal(t, []string{"a", "b"}, p.Tags)
+
+		res, err := p.Run.Invoke(ctx, "world")
+		assert.NoError(t, err)
+		assert.Equal(t, "Hello, world", res)
+	})
+
+	t.Run("blocks", func(t *testing.T) {
+		inst := ucl.New()
+
+		inv, err := ucl.EvalAs[ucl.Invokable](ctx, inst, `{ |x| add $x 1 }`)
+		assert.NoError(t, err)
+
+		res, err := inv.Invoke(ctx, 41)
+		assert.NoError(t, err)
+		assert.Equal(t, 42, res)
+
+		_, err = ucl.EvalAs[ucl.Invokable](ctx, inst, `cat "not a block"`)
+		assert.Error(t, err)
+	})
+}
+
+func TestInst_Call(t *testing.T) {
+	tests := []struct {
+		desc    string
+		expr    string
+		call    string
+		args    []any
+		want    any
+		wantErr bool
+	}{
+		{desc: "call proc", expr: `proc greet { |x| cat "Hello, " $x }`, call: "greet", args: []any{"world"}, want: "Hello, world"},
+		{desc: "call proc with return", expr: `proc onSave { |doc| return (cat "saved " $doc.name) ; echo "not me" }`,
+			call: "onSave", args: []any{map[string]any{"name": "doc.txt"}}, want: "saved doc.txt"},
+		{desc: "call proc with no args", expr: `proc count { len $hooks } ; set hooks [a b c]`, call: "count", want: 3},
+		{desc: "call builtin", call: "add", args: []any{1, 2, 3}, want: 6},
+		{desc: "call builtin with switches", call: "join", args: []any{"a", "b", ucl.Switches{"sep": "-", "upcase": nil}}, want: "A-B"},
+		{desc: "unknown command", call: "missing", wantErr: true},
+		{desc: "error from proc", expr: `proc badJoin { join "a" }`, call: "badJoin", wantErr: true},
+		{desc: "error from builtin", call: "failing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ctx := context.Background()
+
+			inst := ucl.New()
+			inst.SetBuiltin("join", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+				var (
+					l, r, sep string
+					upcase    bool
+				)
+				if err := args.Bind(&l, &r); err != nil {
+					return nil, err
+				}
+				if err := args.BindSwitch("sep", &sep); err != nil {
+					return nil, err
+				}
+				if err := args.BindSwitch("upcase", &upcase); err != nil {
+					return nil, err
+				}
+
+				if upcase {
+					return strings.ToUpper(l + sep + r), nil
+				}
+				return l + sep + r, nil
+			})
+			inst.SetBuiltin("failing", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+				return nil, errors.New("failed")
+			})
+
+			_, err := inst.Eval(ctx, tt.expr)
+			assert.NoError(t, err)
+
+			res, err := inst.Call(ctx, tt.call, tt.args...)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+		})
+	}
+}
+
+func TestInst_LookupProc(t *testing.T) {
+	ctx := context.Background()
+
+	inst := ucl.New()
+	_, err := inst.Eval(ctx, `
+		set saved []
+		proc onSave { |doc| set saved (append $saved $doc) ; len $saved }
+	`)
+	assert.NoError(t, err)
+
+	onSave, ok := inst.LookupProc("onSave")
+	assert.True(t, ok)
+
+	res, err := onSave.Invoke(ctx, "one")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res)
+
+	res, err = onSave.Invoke(ctx, "two")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res)
+
+	saved, err := inst.Eval(ctx, `$saved`)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"one", "two"}, saved)
+
+	_, ok = inst.LookupProc("onLoad")
+	assert.False(t, ok)
+
+	_, ok = inst.LookupProc("echo")
+	assert.False(t, ok)
+}
+
+func TestInst_VarsAndProcs(t *testing.T) {
+	ctx := context.Background()
+
+	inst := ucl.New()
+	_, err := inst.Eval(ctx, `
+		set name "test"
+		set nums [1 2]
+		proc second { |xs| index $xs 1 }
+		proc first { |xs| index $xs 0 }
+		proc { echo "anonymous" }
+	`)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"hello", "name", "nums"}, inst.VarNames())
+	assert.Equal(t, []string{"first", "second"}, inst.ProcNames())
+
+	v, ok := inst.LookupVar("nums")
+	assert.True(t, ok)
+	assert.Equal(t, []any{1, 2}, v)
+
+	v, ok = inst.LookupVar("missing")
+	assert.False(t, ok)
+	assert.Nil(t, v)
+}
+
+func TestIsIncomplete(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{expr: `echo "hello"`, want: false},
+		{expr: ``, want: false},
+		{expr: `proc greet {`, want: true},
+		{expr: "proc greet { |x|\n  echo $x", want: true},
+		{expr: "proc greet { |x|\n  echo $x\n}", want: false},
+		{expr: `echo [1 2`, want: true},
+		{expr: `echo (add 1`, want: true},
+		{expr: `echo (add 1 [2 {`, want: true},
+		{expr: `echo "hello`, want: true},
+		{expr: `echo "{"`, want: false},
+		{expr: `echo 1 # {`, want: false},
+		{expr: `echo }`, want: false},
+		{expr: `echo @`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			assert.Equal(t, tt.want, ucl.IsIncomplete(tt.expr))
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package ucl
+
+import (
+	"context"
+)
+
+// Iterator is a lazy sequence of values, such as the lines of a large file, which are produced
+// one at a time as the sequence is consumed by foreach, map, filter, reduce or head. An
+// iterator can only be consumed once, and is closed once a builtin has finished consuming it,
+// including when take or head only uses some of the values.
+type Iterator struct {
+	next  func(ctx context.Context) (any, bool, error)
+	close func()
+}
+
+// NewIterator returns an Iterator which calls next to produce each value of the sequence. The
+// function returns false once the sequence has been exhausted.
+func NewIterator(next func(ctx context.Context) (any, bool, error)) Iterator {
+	return Iterator{next: next}
+}
+
+// NewIteratorWithClose returns an Iterator like NewIterator, which calls close once the
+// iterator has been consumed, to release any resources held by the sequence. Close is called
+// even if the sequence has not been exhausted, and may be called more than once.
+func NewIteratorWithClose(next func(ctx context.Context) (any, bool, error), close func()) Iterator {
+	return Iterator{next: next, close: close}
+}
+
+func (it Iterator) String() string {
+	return "(iterator)"
+}
+
+func (it Iterator) Truthy() bool {
+	return it.next != nil
+}
+
+// Next returns the next value of the sequence, or false if the sequence has been exhausted.
+func (it Iterator) Next(ctx context.Context) (any, bool, error) {
+	if it.next == nil {
+		return nil, false, nil
+	}
+	return it.next(ctx)
+}
+
+// Close releases the resources of the sequence, discarding any remaining values.
+func (it Iterator) Close() {
+	if it.close != nil {
+		it.close()
+	}
+}
+
+func (it Iterator) nextObject(ctx context.Context) (object, bool, error) {
+	v, ok, err := it.Next(ctx)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	o, err := fromGoValue(v)
+	if err != nil {
+		return nil, false, err
+	}
+	return o, true, nil
+}
+
+// each calls fn with each remaining value of the sequence, stopping at the first error. The
+// iterator is closed once each returns.
+func (it Iterator) each(ctx context.Context, fn func(v object) error) error {
+	defer it.Close()
+
+	for {
+		v, ok, err := it.nextObject(ctx)
+		if err != nil {
+			return err
+		} else if !ok {
+			return nil
+		}
+
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+}
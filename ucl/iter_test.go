@@ -0,0 +1,56 @@
+package ucl_test
+
+import (
+	"context"
+	"testing"
+
+	"ucl.lmika.dev/ucl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterator_Consume(t *testing.T) {
+	tests := []struct {
+		desc string
+		expr string
+		want any
+	}{
+		{desc: "map", expr: `counter 3 | map { |x| add $x 1 }`, want: []any{1, 2, 3}},
+		{desc: "filter", expr: `counter 4 | filter { |x| eq $x 2 }`, want: []any{2}},
+		{desc: "reduce", expr: `counter 4 | reduce { |x a| add $x $a }`, want: 6},
+		{desc: "head", expr: `head (counter 3)`, want: 0},
+		{desc: "head empty", expr: `head (counter 0)`, want: nil},
+		{desc: "foreach", expr: `set s 0 ; foreach (counter 4) { |x| set s (add $s $x) } ; $s`, want: 6},
+		{desc: "foreach break", expr: `foreach (counter 10) { |x| if (eq $x 2) { break $x } }`, want: 2},
+		{desc: "as result", expr: `counter 2`, want: "(iterator)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			inst := ucl.New()
+			inst.SetBuiltin("counter", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+				var n int
+				if err := args.Bind(&n); err != nil {
+					return nil, err
+				}
+
+				i := 0
+				return ucl.NewIterator(func(ctx context.Context) (any, bool, error) {
+					if i >= n {
+						return nil, false, nil
+					}
+					i++
+					return i - 1, true, nil
+				}), nil
+			})
+
+			res, err := inst.Eval(context.Background(), tt.expr)
+			assert.NoError(t, err)
+			if it, ok := res.(ucl.Iterator); ok {
+				assert.Equal(t, tt.want, it.String())
+			} else {
+				assert.Equal(t, tt.want, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,761 @@
+package ucl
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// listArg returns argument i as a listable. Iterators are consumed into a list.
+func (ia invocationArgs) listArg(ctx context.Context, i int) (listable, error) {
+	if len(ia.args) <= i {
+		return nil, fmt.Errorf("expected at least %d args", i+1)
+	}
+
+	switch t := ia.args[i].(type) {
+	case listable:
+		return t, nil
+	case Iterator:
+		l := &listObject{}
+		if err := t.each(ctx, func(v object) error {
+			l.Append(v)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+	return nil, errors.New("expected listable")
+}
+
+// optInvokableArg returns argument i as an invokable, or nil if there is no argument i.
+func (ia invocationArgs) optInvokableArg(i int) (invokable, error) {
+	if len(ia.args) <= i {
+		return nil, nil
+	}
+	return ia.invokableArg(i)
+}
+
+// listElems returns a copy of the elements of l, which can be modified without affecting l.
+func listElems(l listable) []object {
+	elems := make([]object, l.Len())
+	for i := range elems {
+		elems[i] = l.Index(i)
+	}
+	return elems
+}
+
+// listKeys returns the elements of l transformed by the key block, or the elements themselves
+// if there is no key block.
+func listKeys(ctx context.Context, args invocationArgs, elems []object, keyFn invokable) ([]object, error) {
+	if keyFn == nil {
+		return elems, nil
+	}
+
+	keys := make([]object, len(elems))
+	for i, e := range elems {
+		k, err := keyFn.invoke(ctx, args.fork([]object{e}))
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = k
+	}
+	return keys, nil
+}
+
+// compareObjects orders two values of the same kind. Ints and floats are compared
+// numerically, strings lexically, and false is ordered before true. Nil is ordered before
+// every other value.
+func compareObjects(l, r object) (int, error) {
+	if l == nil || r == nil {
+		switch {
+		case l == nil && r == nil:
+			return 0, nil
+		case l == nil:
+			return -1, nil
+		}
+		return 1, nil
+	}
+
+	if lf, ok := numberValue(l); ok {
+		if rf, ok := numberValue(r); ok {
+			switch {
+			case lf < rf:
+				return -1, nil
+			case lf > rf:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+
+	switch lv := l.(type) {
+	case strObject:
+		if rv, ok := r.(strObject); ok {
+			return strings.Compare(string(lv), string(rv)), nil
+		}
+	case boolObject:
+		if rv, ok := r.(boolObject); ok {
+			switch {
+			case lv == rv:
+				return 0, nil
+			case !bool(lv):
+				return -1, nil
+			}
+			return 1, nil
+		}
+	case timeObject:
+		if rv, ok := r.(timeObject); ok {
+			return time.Time(lv).Compare(time.Time(rv)), nil
+		}
+	case durationObject:
+		if rv, ok := r.(durationObject); ok {
+			return cmp.Compare(lv, rv), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %v with %v", typeName(l), typeName(r))
+}
+
+func numberValue(obj object) (float64, bool) {
+	switch t := obj.(type) {
+	case intObject:
+		return float64(t), true
+	case floatObject:
+		return float64(t), true
+	}
+	return 0, false
+}
+
+// clampListPos converts a position, which may be negative to count from the end, into an
+// index between 0 and n inclusive.
+func clampListPos(pos, n int) int {
+	if pos < 0 {
+		pos += n
+	}
+	return max(0, min(pos, n))
+}
+
+func sortBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	keyFn, err := args.optInvokableArg(1)
+	if err != nil {
+		return nil, err
+	}
+
+	elems := listElems(l)
+	keys, err := listKeys(ctx, args, elems, keyFn)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := make([]int, len(elems))
+	for i := range idx {
+		idx[i] = i
+	}
+
+	desc := args.hasSwitch("desc")
+	var cmpErr error
+	sort.SliceStable(idx, func(i, j int) bool {
+		c, err := compareObjects(keys[idx[i]], keys[idx[j]])
+		if err != nil && cmpErr == nil {
+			cmpErr = err
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	if cmpErr != nil {
+		return nil, cmpErr
+	}
+
+	sorted := make([]object, len(idx))
+	for i, n := range idx {
+		sorted[i] = elems[n]
+	}
+	return &listObject{elems: sorted}, nil
+}
+
+func uniqBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	keyFn, err := args.optInvokableArg(1)
+	if err != nil {
+		return nil, err
+	}
+
+	elems := listElems(l)
+	keys, err := listKeys(ctx, args, elems, keyFn)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	res := &listObject{}
+	for i, e := range elems {
+		// The source form is used as the key so that values of different types remain distinct
+		k := repr(keys[i])
+		if !seen[k] {
+			seen[k] = true
+			res.Append(e)
+		}
+	}
+	return res, nil
+}
+
+func reverseBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]object, l.Len())
+	for i := range res {
+		res[i] = l.Index(l.Len() - 1 - i)
+	}
+	return &listObject{elems: res}, nil
+}
+
+func takeBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	n, err := args.intArg(1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the required elements are consumed from iterators, which may be unbounded
+	if it, ok := args.args[0].(Iterator); ok {
+		defer it.Close()
+
+		res := &listObject{}
+		for res.Len() < n {
+			v, hasNext, err := it.nextObject(ctx)
+			if err != nil {
+				return nil, err
+			} else if !hasNext {
+				break
+			}
+			res.Append(v)
+		}
+		return res, nil
+	}
+
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &listObject{elems: listElems(l)[:max(0, min(n, l.Len()))]}, nil
+}
+
+func skipBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	n, err := args.intArg(1)
+	if err != nil {
+		return nil, err
+	}
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &listObject{elems: listElems(l)[max(0, min(n, l.Len())):]}, nil
+}
+
+func tailBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	n, err := args.intArg(1)
+	if err != nil {
+		return nil, err
+	}
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &listObject{elems: listElems(l)[max(0, l.Len()-max(0, n)):]}, nil
+}
+
+func sliceBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	start, err := args.intArg(1)
+	if err != nil {
+		return nil, err
+	}
+
+	end := l.Len()
+	if len(args.args) > 2 {
+		if end, err = args.intArg(2); err != nil {
+			return nil, err
+		}
+	}
+
+	start, end = clampListPos(start, l.Len()), clampListPos(end, l.Len())
+	if start >= end {
+		return &listObject{}, nil
+	}
+	return &listObject{elems: listElems(l)[start:end]}, nil
+}
+
+func flattenBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	depth := 1
+	if args.hasSwitch("deep") {
+		depth = -1
+	}
+
+	res := &listObject{}
+	flattenInto(res, l, depth)
+	return res, nil
+}
+
+func flattenInto(res *listObject, l listable, depth int) {
+	for i := 0; i < l.Len(); i++ {
+		v := l.Index(i)
+		if sl, ok := v.(listable); ok && depth != 0 {
+			flattenInto(res, sl, depth-1)
+		} else {
+			res.Append(v)
+		}
+	}
+}
+
+func zipBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(1); err != nil {
+		return nil, err
+	}
+
+	lists := make([]listable, len(args.args))
+	n := -1
+	for i := range args.args {
+		l, err := args.listArg(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		lists[i] = l
+		if n < 0 || l.Len() < n {
+			n = l.Len()
+		}
+	}
+
+	res := make([]object, n)
+	for i := range res {
+		tuple := make([]object, len(lists))
+		for j, l := range lists {
+			tuple[j] = l.Index(i)
+		}
+		res[i] = &listObject{elems: tuple}
+	}
+	return &listObject{elems: res}, nil
+}
+
+func enumerateBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]object, l.Len())
+	for i := range res {
+		res[i] = &listObject{elems: []object{intObject(i), l.Index(i)}}
+	}
+	return &listObject{elems: res}, nil
+}
+
+func groupByBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(2); err != nil {
+		return nil, err
+	}
+
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	keyFn, err := args.invokableArg(1)
+	if err != nil {
+		return nil, err
+	}
+
+	res := newHashObject(0)
+	for _, e := range listElems(l) {
+		k, err := keyFn.invoke(ctx, args.fork([]object{e}))
+		if err != nil {
+			return nil, err
+		}
+
+		var key string
+		if k != nil {
+			key = k.String()
+		}
+		group, ok := res.Value(key).(*listObject)
+		if !ok {
+			group = &listObject{}
+			res.set(key, group)
+		}
+		group.Append(e)
+	}
+	return res, nil
+}
+
+func partitionBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(2); err != nil {
+		return nil, err
+	}
+
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	pred, err := args.invokableArg(1)
+	if err != nil {
+		return nil, err
+	}
+
+	matching, rest := &listObject{}, &listObject{}
+	for _, e := range listElems(l) {
+		m, err := pred.invoke(ctx, args.fork([]object{e}))
+		if err != nil {
+			return nil, err
+		}
+
+		if isTruthy(m) {
+			matching.Append(e)
+		} else {
+			rest.Append(e)
+		}
+	}
+	return &listObject{elems: []object{matching, rest}}, nil
+}
+
+func chunkBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	size, err := args.intArg(1)
+	if err != nil {
+		return nil, err
+	} else if size <= 0 {
+		return nil, errors.New("chunk size must be greater than 0")
+	}
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	elems := listElems(l)
+	res := &listObject{}
+	for i := 0; i < len(elems); i += size {
+		// Limit the capacity of each chunk so that pushing to one does not change the next
+		j := min(i+size, len(elems))
+		res.Append(&listObject{elems: elems[i:j:j]})
+	}
+	return res, nil
+}
+
+// findMatch returns the index of the first element of l for which pred returns a truthy
+// value, or the first truthy element if pred is nil. It returns -1 if there is no match.
+func findMatch(ctx context.Context, args invocationArgs, l listable, pred invokable, want bool) (int, error) {
+	for i := 0; i < l.Len(); i++ {
+		m := l.Index(i)
+		if pred != nil {
+			var err error
+			if m, err = pred.invoke(ctx, args.fork([]object{m})); err != nil {
+				return -1, err
+			}
+		}
+
+		if isTruthy(m) == want {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+func anyBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	pred, err := args.optInvokableArg(1)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := findMatch(ctx, args, l, pred, true)
+	if err != nil {
+		return nil, err
+	}
+	return boolObject(i >= 0), nil
+}
+
+func allBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	pred, err := args.optInvokableArg(1)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := findMatch(ctx, args, l, pred, false)
+	if err != nil {
+		return nil, err
+	}
+	return boolObject(i < 0), nil
+}
+
+func findBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(2); err != nil {
+		return nil, err
+	}
+
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	pred, err := args.invokableArg(1)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := findMatch(ctx, args, l, pred, true)
+	if err != nil || i < 0 {
+		return nil, err
+	}
+	return l.Index(i), nil
+}
+
+func sumBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	l, err := args.listArg(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		isum    int
+		fsum    float64
+		isFloat bool
+	)
+	for i := 0; i < l.Len(); i++ {
+		switch t := l.Index(i).(type) {
+		case intObject:
+			isum += int(t)
+		case floatObject:
+			fsum += float64(t)
+			isFloat = true
+		default:
+			return nil, fmt.Errorf("element %v is not a number", i)
+		}
+	}
+
+	if isFloat {
+		return floatObject(fsum + float64(isum)), nil
+	}
+	return intObject(isum), nil
+}
+
+// extremeBuiltin returns a builtin which returns the element of a list for which the key
+// compares to the current best with the wanted sign.
+func extremeBuiltin(want int) invokableFunc {
+	return func(ctx context.Context, args invocationArgs) (object, error) {
+		l, err := args.listArg(ctx, 0)
+		if err != nil {
+			return nil, err
+		}
+		keyFn, err := args.optInvokableArg(1)
+		if err != nil {
+			return nil, err
+		}
+
+		elems := listElems(l)
+		keys, err := listKeys(ctx, args, elems, keyFn)
+		if err != nil {
+			return nil, err
+		}
+
+		best := -1
+		for i := range elems {
+			if best < 0 {
+				best = i
+				continue
+			}
+
+			c, err := compareObjects(keys[i], keys[best])
+			if err != nil {
+				return nil, err
+			} else if c == want {
+				best = i
+			}
+		}
+
+		if best < 0 {
+			return nil, nil
+		}
+		return elems[best], nil
+	}
+}
+
+func rangeBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(1); err != nil {
+		return nil, err
+	}
+
+	bounds := make([]int, len(args.args))
+	for i := range bounds {
+		n, err := args.intArg(i)
+		if err != nil {
+			return nil, err
+		}
+		bounds[i] = n
+	}
+
+	start, end, step := 0, bounds[0], 1
+	if len(bounds) > 1 {
+		start, end = bounds[0], bounds[1]
+	}
+	if len(bounds) > 2 {
+		step = bounds[2]
+	}
+	if step == 0 {
+		return nil, errors.New("range step cannot be 0")
+	}
+
+	res := &listObject{}
+	for i := start; (step > 0 && i < end) || (step < 0 && i > end); i += step {
+		res.Append(intObject(i))
+	}
+	return res, nil
+}
+
+func concatListsBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	res := &listObject{}
+	for i := range args.args {
+		l, err := args.listArg(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		res.Append(listElems(l)...)
+	}
+	return res, nil
+}
+
+// pushBuiltin adds values to the end of a list in place. Go slices are modified in place if
+// they are settable, such as those reached through a pointer.
+func pushBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(1); err != nil {
+		return nil, err
+	}
+
+	switch t := args.args[0].(type) {
+	case *listObject:
+		t.Append(args.args[1:]...)
+		return t, nil
+	case listableProxyObject:
+		if !t.v.CanSet() {
+			break
+		}
+
+		newSlice := t.v
+		for i, a := range args.args[1:] {
+			ev, err := args.toGoReflectValue(a, t.v.Type().Elem())
+			if err != nil {
+				return nil, fmt.Errorf("arg %v of 'push': %w", i+1, err)
+			}
+			newSlice = reflect.Append(newSlice, ev)
+		}
+		t.v.Set(newSlice)
+		return t, nil
+	}
+	return nil, fmt.Errorf("cannot push to %v", typeName(args.args[0]))
+}
+
+func unshiftBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(1); err != nil {
+		return nil, err
+	}
+
+	switch t := args.args[0].(type) {
+	case *listObject:
+		newElems := make([]object, 0, t.Len()+len(args.args)-1)
+		newElems = append(newElems, args.args[1:]...)
+		t.elems = append(newElems, t.elems...)
+		return t, nil
+	case listableProxyObject:
+		if !t.v.CanSet() {
+			break
+		}
+
+		n := len(args.args) - 1
+		newSlice := reflect.MakeSlice(t.v.Type(), n+t.v.Len(), n+t.v.Len())
+		for i, a := range args.args[1:] {
+			ev, err := args.toGoReflectValue(a, t.v.Type().Elem())
+			if err != nil {
+				return nil, fmt.Errorf("arg %v of 'unshift': %w", i+1, err)
+			}
+			newSlice.Index(i).Set(ev)
+		}
+		reflect.Copy(newSlice.Slice(n, newSlice.Len()), t.v)
+		t.v.Set(newSlice)
+		return t, nil
+	}
+	return nil, fmt.Errorf("cannot unshift to %v", typeName(args.args[0]))
+}
+
+func popBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	return removeListEnd(args, "pop", func(n int) int { return n - 1 })
+}
+
+func shiftBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	return removeListEnd(args, "shift", func(n int) int { return 0 })
+}
+
+// removeListEnd removes the element at the index returned by idx, which is either the first
+// or last element of the list, and returns it. Nil is returned if the list is empty.
+func removeListEnd(args invocationArgs, name string, idx func(n int) int) (object, error) {
+	if err := args.expectArgn(1); err != nil {
+		return nil, err
+	}
+
+	switch t := args.args[0].(type) {
+	case *listObject:
+		n := t.Len()
+		if n == 0 {
+			return nil, nil
+		}
+
+		i := idx(n)
+		v := t.elems[i]
+		t.elems = append(t.elems[:i:i], t.elems[i+1:]...)
+		return v, nil
+	case listableProxyObject:
+		if !t.v.CanSet() {
+			break
+		}
+
+		n := t.v.Len()
+		if n == 0 {
+			return nil, nil
+		}
+
+		// The element is copied, as the backing array of the slice may be reused
+		i := idx(n)
+		v := reflect.New(t.v.Type().Elem()).Elem()
+		v.Set(t.v.Index(i))
+		if i == 0 {
+			t.v.Set(t.v.Slice(1, n))
+		} else {
+			t.v.Set(t.v.Slice(0, i))
+		}
+		return fromGoReflectElem(v), nil
+	}
+	return nil, fmt.Errorf("cannot %v from %v", name, typeName(args.args[0]))
+}
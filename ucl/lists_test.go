@@ -0,0 +1,191 @@
+package ucl
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltins_Lists(t *testing.T) {
+	type person struct {
+		Name string
+		Age  int
+	}
+
+	tests := []struct {
+		desc    string
+		expr    string
+		want    any
+		wantErr bool
+	}{
+		{desc: "sort ints", expr: `sort [3 1 2]`, want: []any{1, 2, 3}},
+		{desc: "sort mixed numbers", expr: `sort [3 1.5 2]`, want: []any{1.5, 2, 3}},
+		{desc: "sort strings", expr: `sort ["b" "c" "a"]`, want: []any{"a", "b", "c"}},
+		{desc: "sort desc", expr: `sort [3 1 2] -desc`, want: []any{3, 2, 1}},
+		{desc: "sort key", expr: `sort ["ccc" "a" "bb"] { |x| len $x }`, want: []any{"a", "bb", "ccc"}},
+		{desc: "sort key desc", expr: `sort ["ccc" "a" "bb"] { |x| len $x } -desc`, want: []any{"ccc", "bb", "a"}},
+		{desc: "sort stable", expr: `sort [[2 "a"] [1 "b"] [2 "c"]] { |x| index $x 0 }`, want: []any{[]any{1, "b"}, []any{2, "a"}, []any{2, "c"}}},
+		{desc: "sort pipe", expr: `[3 1 2] | sort`, want: []any{1, 2, 3}},
+		{desc: "sort proxy", expr: `goSlice | sort`, want: []any{1, 2, 3}},
+		{desc: "sort incomparable", expr: `sort [1 "a"]`, wantErr: true},
+
+		{desc: "uniq", expr: `uniq [1 2 1 3 2]`, want: []any{1, 2, 3}},
+		{desc: "uniq keeps types", expr: `uniq [1 "1" 1]`, want: []any{1, "1"}},
+		{desc: "uniq key", expr: `uniq ["a" "bb" "c"] { |x| len $x }`, want: []any{"a", "bb"}},
+		{desc: "reverse", expr: `reverse [1 2 3]`, want: []any{3, 2, 1}},
+
+		{desc: "take", expr: `take [1 2 3] 2`, want: []any{1, 2}},
+		{desc: "take more than len", expr: `take [1 2 3] 5`, want: []any{1, 2, 3}},
+		{desc: "take iterator", expr: `take (counter) 3`, want: []any{0, 1, 2}},
+		{desc: "take then append", expr: `set xs [1 2 3] ; take $xs 1 | append 9 ; $xs`, want: []any{1, 2, 3}},
+		{desc: "skip", expr: `skip [1 2 3] 1`, want: []any{2, 3}},
+		{desc: "skip all", expr: `skip [1 2 3] 5`, want: []any{}},
+		{desc: "tail", expr: `tail [1 2 3] 2`, want: []any{2, 3}},
+		{desc: "tail more than len", expr: `tail [1 2 3] 5`, want: []any{1, 2, 3}},
+		{desc: "slice", expr: `slice [1 2 3 4] 1 3`, want: []any{2, 3}},
+		{desc: "slice to end", expr: `slice [1 2 3 4] 2`, want: []any{3, 4}},
+		{desc: "slice negative", expr: `slice [1 2 3 4] -3 -1`, want: []any{2, 3}},
+		{desc: "slice empty", expr: `slice [1 2 3 4] 3 1`, want: []any{}},
+
+		{desc: "flatten", expr: `flatten [1 [2 [3]] 4]`, want: []any{1, 2, []any{3}, 4}},
+		{desc: "flatten deep", expr: `flatten [1 [2 [3]] 4] -deep`, want: []any{1, 2, 3, 4}},
+		{desc: "zip", expr: `zip [1 2 3] ["a" "b"]`, want: []any{[]any{1, "a"}, []any{2, "b"}}},
+		{desc: "enumerate", expr: `enumerate ["a" "b"]`, want: []any{[]any{0, "a"}, []any{1, "b"}}},
+		{desc: "groupBy", expr: `groupBy ["one" "two" "three"] { |x| len $x }`, want: map[string]any{
+			"3": []any{"one", "two"},
+			"5": []any{"three"},
+		}},
+		{desc: "partition", expr: `partition [1 2 3 4] { |x| eq (index [0 1 0 1 0] $x) 1 }`, want: []any{[]any{1, 3}, []any{2, 4}}},
+		{desc: "groupBy missing block", expr: `groupBy [1 2]`, wantErr: true},
+		{desc: "partition missing block", expr: `[1 2] | partition`, wantErr: true},
+		{desc: "chunk", expr: `chunk [1 2 3 4 5] 2`, want: []any{[]any{1, 2}, []any{3, 4}, []any{5}}},
+		{desc: "chunk push", expr: `set c (chunk [1 2 3 4] 2) ; push (index $c 0) 9 ; $c`, want: []any{[]any{1, 2, 9}, []any{3, 4}}},
+		{desc: "chunk bad size", expr: `chunk [1 2 3] 0`, wantErr: true},
+
+		{desc: "any", expr: `any [1 2 3] { |x| eq $x 2 }`, want: true},
+		{desc: "any none", expr: `any [1 2 3] { |x| eq $x 5 }`, want: false},
+		{desc: "any truthy", expr: `any [0 "" 1]`, want: true},
+		{desc: "all", expr: `all ["a" "b"] { |x| len $x }`, want: true},
+		{desc: "all not", expr: `all ["a" ""] { |x| len $x }`, want: false},
+		{desc: "all empty", expr: `all [] { |x| false }`, want: true},
+		{desc: "find", expr: `find [1 2 3] { |x| eq $x 2 }`, want: 2},
+		{desc: "find none", expr: `find [1 2 3] { |x| eq $x 5 }`, want: nil},
+		{desc: "find missing block", expr: `find [1 2 3]`, wantErr: true},
+		{desc: "find struct proxy", expr: `find (people) { |p| eq $p.Age 30 } | index "Name"`, want: "Bob"},
+
+		{desc: "sum", expr: `sum [1 2 3]`, want: 6},
+		{desc: "sum floats", expr: `sum [1 2.5]`, want: 3.5},
+		{desc: "sum empty", expr: `sum []`, want: 0},
+		{desc: "sum non-number", expr: `sum [1 "a"]`, wantErr: true},
+		{desc: "min", expr: `min [3 1 2]`, want: 1},
+		{desc: "max", expr: `max [3 1 2]`, want: 3},
+		{desc: "max key", expr: `max ["bb" "ccc" "a"] { |x| len $x }`, want: "ccc"},
+		{desc: "min empty", expr: `min []`, want: nil},
+		{desc: "max proxy", expr: `people | max { |p| $p.Age } | index "Name"`, want: "Bob"},
+
+		{desc: "range", expr: `range 4`, want: []any{0, 1, 2, 3}},
+		{desc: "range start", expr: `range 2 5`, want: []any{2, 3, 4}},
+		{desc: "range step", expr: `range 10 0 -3`, want: []any{10, 7, 4, 1}},
+		{desc: "range zero step", expr: `range 0 5 0`, wantErr: true},
+		{desc: "concat", expr: `concat [1 2] [] [3] (goSlice)`, want: []any{1, 2, 3, 3, 1, 2}},
+		{desc: "concat copies", expr: `set a [1] ; set b (concat $a) ; push $b 2 ; $a`, want: []any{1}},
+
+		{desc: "push", expr: `set a [1] ; push $a 2 3 ; $a`, want: []any{1, 2, 3}},
+		{desc: "push pipe", expr: `[] | push 1 | push 2`, want: []any{1, 2}},
+		{desc: "push seen through other references", expr: `set a [1] ; set b $a ; push $a 2 ; $b`, want: []any{1, 2}},
+		{desc: "push nested", expr: `set h [xs:[1]] ; push $h.xs 2 ; $h`, want: map[string]any{"xs": []any{1, 2}}},
+		{desc: "push go slice pointer", expr: `set s (goSlicePtr) ; push $s 4 ; $s`, want: &[]int{3, 1, 2, 4}},
+		{desc: "push go slice", expr: `push (goSlice) 4`, wantErr: true},
+		{desc: "push non-list", expr: `push [a:1] 4`, wantErr: true},
+		{desc: "pop", expr: `set a [1 2 3] ; [(pop $a) $a]`, want: []any{3, []any{1, 2}}},
+		{desc: "pop empty", expr: `pop []`, want: nil},
+		{desc: "pop go slice pointer", expr: `set s (goSlicePtr) ; [(pop $s) (len $s)]`, want: []any{2, 2}},
+		{desc: "shift", expr: `set a [1 2 3] ; [(shift $a) $a]`, want: []any{1, []any{2, 3}}},
+		{desc: "shift empty", expr: `shift []`, want: nil},
+		{desc: "shift go slice pointer", expr: `set s (goSlicePtr) ; [(shift $s) (len $s)]`, want: []any{3, 2}},
+		{desc: "shift loop", expr: `set a [1 2 3] ; set s 0 ; foreach (range 3) { |i| set s (add $s (shift $a)) } ; $s`, want: 6},
+		{desc: "unshift", expr: `set a [3] ; unshift $a 1 2 ; $a`, want: []any{1, 2, 3}},
+		{desc: "unshift go slice pointer", expr: `set s (goSlicePtr) ; unshift $s 5 6 ; $s`, want: &[]int{5, 6, 3, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ctx := context.Background()
+			outW := bytes.NewBuffer(nil)
+
+			inst := New(WithOut(outW), WithTestBuiltin())
+			inst.SetBuiltin("goSlice", func(ctx context.Context, args CallArgs) (any, error) {
+				return []int{3, 1, 2}, nil
+			})
+			inst.SetBuiltin("goSlicePtr", func(ctx context.Context, args CallArgs) (any, error) {
+				return &[]int{3, 1, 2}, nil
+			})
+			inst.SetBuiltin("people", func(ctx context.Context, args CallArgs) (any, error) {
+				return []person{{Name: "Alice", Age: 25}, {Name: "Bob", Age: 30}}, nil
+			})
+			inst.SetBuiltin("counter", func(ctx context.Context, args CallArgs) (any, error) {
+				n := 0
+				return NewIterator(func(ctx context.Context) (any, bool, error) {
+					n++
+					return n - 1, true, nil
+				}), nil
+			})
+
+			res, err := inst.Eval(ctx, tt.expr)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestIterator_Close(t *testing.T) {
+	tests := []struct {
+		desc string
+		expr string
+		want any
+	}{
+		{desc: "take", expr: `take (counter 10) 2`, want: []any{0, 1}},
+		{desc: "head", expr: `head (counter 10)`, want: 0},
+		{desc: "foreach break", expr: `foreach (counter 10) { |x| break $x }`, want: 0},
+		{desc: "map", expr: `map (counter 3) { |x| add $x 1 }`, want: []any{1, 2, 3}},
+		{desc: "filter", expr: `filter (counter 3) { |x| eq $x 1 }`, want: []any{1}},
+		{desc: "reduce", expr: `reduce (counter 3) { |x a| add $x $a }`, want: 3},
+		{desc: "as list", expr: `sort (counter 3)`, want: []any{0, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			closed := 0
+
+			inst := New()
+			inst.SetBuiltin("counter", func(ctx context.Context, args CallArgs) (any, error) {
+				var max int
+				if err := args.Bind(&max); err != nil {
+					return nil, err
+				}
+
+				n := 0
+				return NewIteratorWithClose(func(ctx context.Context) (any, bool, error) {
+					if n >= max {
+						return nil, false, nil
+					}
+					n++
+					return n - 1, true, nil
+				}, func() {
+					closed++
+				}), nil
+			})
+
+			res, err := inst.Eval(context.Background(), tt.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+			assert.Equal(t, 1, closed)
+		})
+	}
+}
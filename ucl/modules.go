@@ -0,0 +1,302 @@
+package ucl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/lmika/gopkgs/fp/maps"
+	"github.com/lmika/gopkgs/fp/slices"
+)
+
+// Module is a collection of builtins, macros, variables and procs made available to scripts
+// under a common prefix, such as "fs:lines" or "$math:pi".
+type Module struct {
+	Name     string
+	Builtins map[string]BuiltinHandler
+	Macros   map[string]MacroHandler
+
+	// Docs describes the builtins and macros of the module, keyed by name.
+	Docs map[string]Doc
+
+	// Vars are constants made available as variables, converted to UCL values.
+	Vars map[string]any
+
+	// Source is UCL source evaluated when the instance is created. Any procs it defines are
+	// made available as builtins of the module.
+	Source string
+
+	// Init is called once the module has been added to the instance, after any Source has
+	// been evaluated. An error returned from Init will be returned by every evaluation and call
+	// made with the instance.
+	Init func(ctx context.Context, inst *Inst) error
+}
+
+// ModuleInfo describes a module added to an instance, either by an InstOption or by a script
+// using import.
+type ModuleInfo struct {
+	Name       string
+	Unprefixed bool
+	Builtins   []string
+	Macros     []string
+	Vars       []string
+}
+
+// WithModule adds the module to the instance, with the members prefixed by the module name.
+func WithModule(module Module) InstOption {
+	return func(i *Inst) {
+		i.addModule(module, module.Name)
+	}
+}
+
+// WithUnprefixedModule adds the module to the instance without prefixing the members by the
+// module name, making them available as if they were regular builtins.
+func WithUnprefixedModule(module Module) InstOption {
+	return func(i *Inst) {
+		i.addModule(module, "")
+	}
+}
+
+// WithModuleFS sets the file system used to resolve the paths of script modules loaded using
+// import. If not set, paths are resolved from the host file system.
+func WithModuleFS(fsys fs.FS) InstOption {
+	return func(i *Inst) {
+		i.moduleFS = fsys
+	}
+}
+
+type instModule struct {
+	module Module
+	prefix string
+	procs  []string
+}
+
+func (m *instModule) qualify(name string) string {
+	if m.prefix == "" {
+		return name
+	}
+	return m.prefix + ":" + name
+}
+
+func (m *instModule) info() ModuleInfo {
+	mi := ModuleInfo{
+		Name:       m.module.Name,
+		Unprefixed: m.prefix == "",
+		Builtins:   append(maps.Keys(m.module.Builtins), m.procs...),
+		Macros:     maps.Keys(m.module.Macros),
+		Vars:       maps.Keys(m.module.Vars),
+	}
+	sort.Strings(mi.Builtins)
+	sort.Strings(mi.Macros)
+	sort.Strings(mi.Vars)
+	return mi
+}
+
+func (inst *Inst) addModule(module Module, prefix string) {
+	m := &instModule{module: module, prefix: prefix}
+	for name, builtin := range module.Builtins {
+		inst.SetBuiltin(m.qualify(name), builtin)
+	}
+	for name, macro := range module.Macros {
+		inst.rootEC.addMacro(m.qualify(name), userMacro{fn: macro})
+	}
+	for name, doc := range module.Docs {
+		inst.SetDoc(m.qualify(name), doc)
+	}
+	inst.modules = append(inst.modules, m)
+}
+
+// addPrefixedMembers makes the members of the module available to ec prefixed with the
+// module name. This allows the module source to refer to them regardless of how the module
+// was added.
+func (m *instModule) addPrefixedMembers(ec *evalCtx) error {
+	if m.prefix == m.module.Name {
+		return nil
+	}
+
+	for name, builtin := range m.module.Builtins {
+		ec.addCmd(m.module.Name+":"+name, userBuiltin{fn: builtin})
+	}
+	for name, macro := range m.module.Macros {
+		ec.addMacro(m.module.Name+":"+name, userMacro{fn: macro})
+	}
+	for name, val := range m.module.Vars {
+		o, err := fromGoValue(val)
+		if err != nil {
+			return err
+		}
+		ec.defineConst(m.module.Name+":"+name, o)
+	}
+	return nil
+}
+
+func (inst *Inst) initModules(ctx context.Context) error {
+	for _, m := range inst.modules {
+		for name, val := range m.module.Vars {
+			o, err := fromGoValue(val)
+			if err != nil {
+				return fmt.Errorf("module %v: var %v: %w", m.module.Name, name, err)
+			}
+			inst.rootEC.defineConst(m.qualify(name), o)
+		}
+
+		if m.module.Source != "" {
+			sm, err := inst.evalScriptModule(ctx, "module:"+m.module.Name, []byte(m.module.Source), m.addPrefixedMembers)
+			if err != nil {
+				return err
+			}
+			for _, name := range sm.procNames() {
+				inst.rootEC.addCmd(m.qualify(name), sm.ec.commands[name])
+				m.procs = append(m.procs, name)
+			}
+		}
+
+		if m.module.Init != nil {
+			if err := m.module.Init(ctx, inst); err != nil {
+				return fmt.Errorf("module %v: %w", m.module.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Modules returns information about the modules added to the instance, sorted by name.
+func (inst *Inst) Modules() []ModuleInfo {
+	mis := slices.Map(inst.modules, func(m *instModule) ModuleInfo { return m.info() })
+	sort.SliceStable(mis, func(i, j int) bool { return mis[i].Name < mis[j].Name })
+	return mis
+}
+
+type scriptModule struct {
+	path string
+	ec   *evalCtx
+}
+
+// procNames returns the names of the procs defined by the module, excluding anything the
+// module imported itself.
+func (sm *scriptModule) procNames() []string {
+	names := make([]string, 0, len(sm.ec.commands))
+	for name := range sm.ec.commands {
+		if !strings.Contains(name, ":") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// importBuiltin evaluates a script module and makes its procs available under a prefix:
+//
+//	import "lib/util.ucl" as util
+//
+// If no name is given, the base name of the file without the extension is used. Within a
+// script module, PATH is relative to the directory of the module.
+func importBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(1); err != nil {
+		return nil, err
+	}
+
+	modPath, err := args.stringArg(0)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(path.Base(modPath), path.Ext(modPath))
+	switch {
+	case len(args.args) == 3 && args.args[1] != nil && args.args[1].String() == "as":
+		name, err = args.stringArg(2)
+		if err != nil {
+			return nil, err
+		}
+	case len(args.args) != 1:
+		return nil, errors.New("malformed import: expected 'import PATH [as NAME]'")
+	}
+
+	if !path.IsAbs(modPath) {
+		modPath = path.Join(args.ec.root.modDir, modPath)
+	}
+
+	mod, err := args.inst.loadScriptModule(ctx, modPath)
+	if err != nil {
+		return nil, err
+	}
+
+	im := &instModule{module: Module{Name: name}, prefix: name, procs: mod.procNames()}
+	for _, procName := range im.procs {
+		args.ec.addCmd(im.qualify(procName), mod.ec.commands[procName])
+	}
+
+	if args.ec.root == args.inst.rootEC {
+		args.inst.modules = slices.Filter(args.inst.modules, func(m *instModule) bool { return m.prefix != name })
+		args.inst.modules = append(args.inst.modules, im)
+	}
+	return nil, nil
+}
+
+func (inst *Inst) loadScriptModule(ctx context.Context, modPath string) (*scriptModule, error) {
+	modPath = path.Clean(modPath)
+	if mod, ok := inst.scriptModules[modPath]; ok {
+		return mod, nil
+	}
+
+	for _, p := range inst.importing {
+		if p == modPath {
+			return nil, fmt.Errorf("import cycle: %v -> %v", strings.Join(inst.importing, " -> "), modPath)
+		}
+	}
+
+	src, err := inst.readModuleFile(modPath)
+	if err != nil {
+		return nil, err
+	}
+
+	inst.importing = append(inst.importing, modPath)
+	defer func() { inst.importing = inst.importing[:len(inst.importing)-1] }()
+
+	mod, err := inst.evalScriptModule(ctx, modPath, src, func(ec *evalCtx) error {
+		ec.modDir = path.Dir(modPath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if inst.scriptModules == nil {
+		inst.scriptModules = make(map[string]*scriptModule)
+	}
+	inst.scriptModules[modPath] = mod
+	return mod, nil
+}
+
+func (inst *Inst) evalScriptModule(ctx context.Context, modPath string, src []byte, prepareEC func(ec *evalCtx) error) (*scriptModule, error) {
+	ast, err := parse(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", modPath, err)
+	}
+
+	// Modules are evaluated in their own root so that procs they define are not visible to
+	// the importing script unless exported.
+	mod := &scriptModule{path: modPath, ec: inst.rootEC.forkAndIsolate()}
+	if prepareEC != nil {
+		if err := prepareEC(mod.ec); err != nil {
+			return nil, fmt.Errorf("%v: %w", modPath, err)
+		}
+	}
+	if _, err := (evaluator{inst: inst}).evalScript(ctx, mod.ec, ast); err != nil {
+		return nil, fmt.Errorf("%v: %w", modPath, err)
+	}
+	return mod, nil
+}
+
+func (inst *Inst) readModuleFile(modPath string) ([]byte, error) {
+	if inst.moduleFS == nil {
+		return os.ReadFile(modPath)
+	}
+	return fs.ReadFile(inst.moduleFS, modPath)
+}
@@ -0,0 +1,201 @@
+package ucl_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"ucl.lmika.dev/ucl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testModuleFS = fstest.MapFS{
+	"lib/util.ucl": &fstest.MapFile{Data: []byte(`
+		set greeting "Hello"
+
+		proc greet { |who| cat $greeting ", " $who }
+		proc shout { |who| greet $who | toUpper }
+	`)},
+	"lib/strs.ucl": &fstest.MapFile{Data: []byte(`
+		import "util.ucl" as u
+		proc wrap { |s| cat "[" (u:greet $s) "]" }
+	`)},
+	"lib/sub/deep.ucl": &fstest.MapFile{Data: []byte(`
+		import "../strs.ucl"
+		import "inner.ucl"
+		proc wrapInner { strs:wrap (inner:name) }
+	`)},
+	"lib/sub/inner.ucl": &fstest.MapFile{Data: []byte(`
+		proc name { "inner" }
+	`)},
+	"lib/sub/count.ucl": &fstest.MapFile{Data: []byte(`import "../counter.ucl"`)},
+	"lib/counter.ucl": &fstest.MapFile{Data: []byte(`
+		echo "loading counter"
+		proc count { 1 }
+	`)},
+	"cycle/a.ucl": &fstest.MapFile{Data: []byte(`import "b.ucl"`)},
+	"cycle/b.ucl": &fstest.MapFile{Data: []byte(`import "c.ucl"`)},
+	"cycle/c.ucl": &fstest.MapFile{Data: []byte(`import "a.ucl"`)},
+	"bad.ucl":     &fstest.MapFile{Data: []byte(`proc broken {`)},
+}
+
+func TestBuiltins_Import(t *testing.T) {
+	tests := []struct {
+		desc    string
+		expr    string
+		want    any
+		wantOut string
+		wantErr string
+	}{
+		{desc: "import with alias", expr: `import "lib/util.ucl" as util ; util:greet "world"`, want: "Hello, world"},
+		{desc: "import without alias", expr: `import "lib/util.ucl" ; util:shout "world"`, want: "HELLO, WORLD"},
+		{desc: "procs not visible unprefixed", expr: `import "lib/util.ucl" ; greet "world"`, wantErr: "unknown command: greet"},
+		{desc: "module vars not visible", expr: `import "lib/util.ucl" ; $greeting`, want: nil},
+		{desc: "nested imports", expr: `import "lib/strs.ucl" ; strs:wrap "you"`, want: "[Hello, you]"},
+		{desc: "nested imports in subdirectories", expr: `import "lib/sub/deep.ucl" ; deep:wrapInner`, want: "[Hello, inner]"},
+		{desc: "nested imports cached by path", expr: `import "lib/counter.ucl" ; import "lib/sub/count.ucl"`, wantOut: "loading counter\n"},
+		{desc: "nested imports not exported", expr: `import "lib/strs.ucl" ; strs:u:greet "you"`, wantErr: "unknown command: strs:u:greet"},
+		{desc: "modules are cached", expr: `import "lib/counter.ucl" ; import "lib/counter.ucl" as c2 ; add (counter:count) (c2:count)`,
+			want: 2, wantOut: "loading counter\n"},
+		{desc: "import cycles", expr: `import "cycle/a.ucl"`,
+			wantErr: "cycle/a.ucl: cycle/b.ucl: cycle/c.ucl: import cycle: cycle/a.ucl -> cycle/b.ucl -> cycle/c.ucl -> cycle/a.ucl"},
+		{desc: "missing module", expr: `import "missing.ucl"`, wantErr: "open missing.ucl: file does not exist"},
+		{desc: "bad module", expr: `import "bad.ucl"`, wantErr: "bad.ucl: test:1:14: unexpected token \"<EOF>\" (expected <rc>)"},
+		{desc: "malformed import", expr: `import "lib/util.ucl" util`, wantErr: "malformed import: expected 'import PATH [as NAME]'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ctx := context.Background()
+			outW := bytes.NewBuffer(nil)
+
+			inst := ucl.New(ucl.WithOut(outW), ucl.WithModuleFS(testModuleFS))
+			res, err := inst.Eval(ctx, tt.expr)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+				assert.Equal(t, tt.wantOut, outW.String())
+			}
+		})
+	}
+}
+
+func TestInst_WithModule(t *testing.T) {
+	var initCalls int
+
+	mathModule := ucl.Module{
+		Name: "math",
+		Builtins: map[string]ucl.BuiltinHandler{
+			"double": ucl.Func(func(x int) int { return x * 2 }),
+		},
+		Macros: map[string]ucl.MacroHandler{
+			"unless": func(ctx context.Context, args ucl.MacroArgs) (any, error) {
+				guard, err := args.EvalArg(ctx, 0)
+				if err != nil {
+					return nil, err
+				}
+				if guard == nil || guard == "" || guard == 0 {
+					return args.EvalBlock(ctx, 1, "nope")
+				}
+				return nil, nil
+			},
+		},
+		Vars: map[string]any{
+			"answer": 42,
+			"digits": []int{1, 2, 3},
+		},
+		Source: `
+			proc quadruple { |x| math:double (math:double $x) }
+			proc answerPlus { |x| helper $x }
+			proc helper { |x| add $x $math:answer }
+		`,
+		Init: func(ctx context.Context, inst *ucl.Inst) error {
+			initCalls++
+			return nil
+		},
+	}
+
+	tests := []struct {
+		desc    string
+		expr    string
+		want    any
+		wantErr string
+	}{
+		{desc: "builtin", expr: `math:double 4`, want: 8},
+		{desc: "macro", expr: `math:unless () { |x| cat "ran " $x }`, want: "ran nope"},
+		{desc: "macro not run", expr: `math:unless 1 { |x| cat "ran " $x }`, want: nil},
+		{desc: "var", expr: `$math:answer`, want: 42},
+		{desc: "var in list", expr: `$math:digits | index 1`, want: 2},
+		{desc: "var in hash", expr: `[k:$math:answer]`, want: map[string]any{"k": 42}},
+		{desc: "constant var", expr: `set math:answer 12`, wantErr: "cannot set constant: math:answer"},
+		{desc: "proc from source", expr: `math:quadruple 3`, want: 12},
+		{desc: "procs calling each other", expr: `math:answerPlus 3`, want: 45},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			initCalls = 0
+
+			inst := ucl.New(ucl.WithModule(mathModule))
+			assert.Equal(t, 1, initCalls)
+
+			res, err := inst.Eval(context.Background(), tt.expr)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+		})
+	}
+
+	t.Run("unprefixed", func(t *testing.T) {
+		inst := ucl.New(ucl.WithUnprefixedModule(mathModule))
+
+		res, err := inst.Eval(context.Background(), `quadruple $answer`)
+		assert.NoError(t, err)
+		assert.Equal(t, 168, res)
+	})
+
+	t.Run("init error", func(t *testing.T) {
+		inst := ucl.New(ucl.WithModule(ucl.Module{
+			Name: "bad",
+			Init: func(ctx context.Context, inst *ucl.Inst) error {
+				return errors.New("cannot init")
+			},
+		}))
+
+		_, err := inst.Eval(context.Background(), `echo "hello"`)
+		assert.EqualError(t, err, "module bad: cannot init")
+
+		_, err = inst.Eval(context.Background(), `echo "again"`)
+		assert.EqualError(t, err, "module bad: cannot init")
+
+		_, err = inst.Call(context.Background(), "echo", "hello")
+		assert.EqualError(t, err, "module bad: cannot init")
+	})
+
+	t.Run("modules", func(t *testing.T) {
+		inst := ucl.New(
+			ucl.WithModule(mathModule),
+			ucl.WithUnprefixedModule(ucl.Module{Name: "extra", Builtins: map[string]ucl.BuiltinHandler{
+				"noop": ucl.Func(func() {}),
+			}}),
+			ucl.WithModuleFS(testModuleFS),
+		)
+
+		_, err := inst.Eval(context.Background(), `import "lib/util.ucl" as u`)
+		assert.NoError(t, err)
+
+		assert.Equal(t, []ucl.ModuleInfo{
+			{Name: "extra", Unprefixed: true, Builtins: []string{"noop"}, Macros: []string{}, Vars: []string{}},
+			{Name: "math", Builtins: []string{"answerPlus", "double", "helper", "quadruple"}, Macros: []string{"unless"}, Vars: []string{"answer", "digits"}},
+			{Name: "u", Builtins: []string{"greet", "shout"}, Macros: []string{}, Vars: []string{}},
+		}, inst.Modules())
+	})
+}
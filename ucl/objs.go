@@ -5,8 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/lmika/gopkgs/fp/maps"
 	"github.com/lmika/gopkgs/fp/slices"
 )
 
@@ -26,49 +30,108 @@ type hashable interface {
 	Each(func(k string, v object) error) error
 }
 
-type listObject []object
+// listObject is a list of values. It is used as a pointer, so that changes made through
+// set-at or push are seen by every reference to the list.
+type listObject struct {
+	elems []object
+}
+
+func (lo *listObject) Append(o ...object) {
+	lo.elems = append(lo.elems, o...)
+}
+
+func (s *listObject) String() string {
+	return fmt.Sprintf("%v", s.elems)
+}
+
+func (s *listObject) Truthy() bool {
+	return len(s.elems) > 0
+}
+
+func (s *listObject) Len() int {
+	return len(s.elems)
+}
 
-func (lo *listObject) Append(o object) {
-	*lo = append(*lo, o)
+func (s *listObject) Index(i int) object {
+	return s.elems[i]
 }
 
-func (s listObject) String() string {
-	return fmt.Sprintf("%v", []object(s))
+// hashObject is a hash which preserves the order in which keys were first set. It is used as
+// a pointer, so that changes made through set-at are seen by every reference to the hash.
+type hashObject struct {
+	keys []string
+	vals map[string]object
 }
 
-func (s listObject) Truthy() bool {
-	return len(s) > 0
+func newHashObject(size int) *hashObject {
+	return &hashObject{
+		keys: make([]string, 0, size),
+		vals: make(map[string]object, size),
+	}
 }
 
-func (s listObject) Len() int {
-	return len(s)
+func (s *hashObject) String() string {
+	if len(s.keys) == 0 {
+		return "[:]"
+	}
+
+	sb := strings.Builder{}
+	sb.WriteRune('[')
+	for i, k := range s.keys {
+		if i > 0 {
+			sb.WriteRune(' ')
+		}
+		sb.WriteString(k)
+		sb.WriteRune(':')
+		if v := s.vals[k]; v == nil {
+			sb.WriteString("()")
+		} else {
+			sb.WriteString(v.String())
+		}
+	}
+	sb.WriteRune(']')
+	return sb.String()
 }
 
-func (s listObject) Index(i int) object {
-	return s[i]
+func (s *hashObject) Truthy() bool {
+	return len(s.keys) > 0
 }
 
-type hashObject map[string]object
+func (s *hashObject) Len() int {
+	return len(s.keys)
+}
 
-func (s hashObject) String() string {
-	return fmt.Sprintf("%v", map[string]object(s))
+func (s *hashObject) Value(k string) object {
+	return s.vals[k]
 }
 
-func (s hashObject) Truthy() bool {
-	return len(s) > 0
+func (s *hashObject) has(k string) bool {
+	_, ok := s.vals[k]
+	return ok
 }
 
-func (s hashObject) Len() int {
-	return len(s)
+// set sets the value of k. New keys are added to the end of the hash, while existing keys
+// keep their position.
+func (s *hashObject) set(k string, v object) {
+	if _, ok := s.vals[k]; !ok {
+		s.keys = append(s.keys, k)
+	}
+	s.vals[k] = v
 }
 
-func (s hashObject) Value(k string) object {
-	return s[k]
+func (s *hashObject) del(k string) {
+	if _, ok := s.vals[k]; !ok {
+		return
+	}
+
+	delete(s.vals, k)
+	s.keys = slices.Filter(s.keys, func(x string) bool { return x != k })
 }
 
-func (s hashObject) Each(fn func(k string, v object) error) error {
-	for k, v := range s {
-		if err := fn(k, v); err != nil {
+// Each calls fn with each key and value of the hash, in the order the keys were added.
+func (s *hashObject) Each(fn func(k string, v object) error) error {
+	for _, k := range s.keys {
+		if err := fn(k, s.vals[k]); err != nil {
 			return err
 		}
 	}
@@ -95,13 +158,23 @@ func (i intObject) Truthy() bool {
 	return i != 0
 }
 
+type floatObject float64
+
+func (f floatObject) String() string {
+	return strconv.FormatFloat(float64(f), 'f', -1, 64)
+}
+
+func (f floatObject) Truthy() bool {
+	return f != 0
+}
+
 type boolObject bool
 
 func (b boolObject) String() string {
 	if b {
 		return "(true)"
 	}
-	return "(false))"
+	return "(false)"
 }
 
 func (b boolObject) Truthy() bool {
@@ -109,6 +182,12 @@ func (b boolObject) Truthy() bool {
 }
 
 func toGoValue(obj object) (interface{}, bool) {
+	return toGoValueWith(obj, false)
+}
+
+// toGoValueWith converts obj to a Go value, with hashes converted to an *OrderedHash if
+// ordered is set, or a map[string]any otherwise.
+func toGoValueWith(obj object, ordered bool) (interface{}, bool) {
 	switch v := obj.(type) {
 	case OpaqueObject:
 		return v.v, true
@@ -118,20 +197,40 @@ func toGoValue(obj object) (interface{}, bool) {
 		return string(v), true
 	case intObject:
 		return int(v), true
-	case listObject:
-		xs := make([]interface{}, 0, len(v))
-		for _, va := range v {
-			x, ok := toGoValue(va)
+	case floatObject:
+		return float64(v), true
+	case boolObject:
+		return bool(v), true
+	case timeObject:
+		return time.Time(v), true
+	case durationObject:
+		return time.Duration(v), true
+	case Iterator:
+		return v, true
+	case *listObject:
+		xs := make([]interface{}, 0, len(v.elems))
+		for _, va := range v.elems {
+			x, ok := toGoValueWith(va, ordered)
 			if !ok {
 				continue
 			}
 			xs = append(xs, x)
 		}
 		return xs, true
-	case hashObject:
+	case *hashObject:
+		if ordered {
+			xs := NewOrderedHash()
+			for _, k := range v.keys {
+				if x, ok := toGoValueWith(v.vals[k], ordered); ok {
+					xs.Set(k, x)
+				}
+			}
+			return xs, true
+		}
+
 		xs := make(map[string]interface{})
-		for k, va := range v {
-			x, ok := toGoValue(va)
+		for k, va := range v.vals {
+			x, ok := toGoValueWith(va, ordered)
 			if !ok {
 				continue
 			}
@@ -144,6 +243,8 @@ func toGoValue(obj object) (interface{}, bool) {
 		return v.orig.Interface(), true
 	case structProxyObject:
 		return v.orig.Interface(), true
+	case mapProxyObject:
+		return v.orig.Interface(), true
 	}
 
 	return nil, false
@@ -153,12 +254,61 @@ func fromGoValue(v any) (object, error) {
 	switch t := v.(type) {
 	case OpaqueObject:
 		return t, nil
+	case Iterator:
+		return t, nil
+	case Invokable:
+		if o, ok := t.inv.(object); ok {
+			return o, nil
+		}
+		return nil, nil
 	case nil:
 		return nil, nil
 	case string:
 		return strObject(t), nil
 	case int:
 		return intObject(t), nil
+	case float64:
+		return floatObject(t), nil
+	case bool:
+		return boolObject(t), nil
+	case []any:
+		// Untyped lists and hashes, such as those decoded from JSON, are copied into lists and
+		// hashes rather than proxied, so that they behave like those created by scripts. Changes
+		// made to them are not seen by the Go value. Use a typed slice or map, or a pointer to
+		// one, to share it with the script.
+		l := make([]object, len(t))
+		for i, v := range t {
+			o, err := fromGoValue(v)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = o
+		}
+		return &listObject{elems: l}, nil
+	case map[string]any:
+		// Keys are added in sorted order, so that the hash has the same order on every run
+		keys := maps.Keys(t)
+		sort.Strings(keys)
+
+		h := newHashObject(len(t))
+		for _, k := range keys {
+			o, err := fromGoValue(t[k])
+			if err != nil {
+				return nil, err
+			}
+			h.set(k, o)
+		}
+		return h, nil
+	case *OrderedHash:
+		h := newHashObject(t.Len())
+		for _, k := range t.keys {
+			o, err := fromGoValue(t.vals[k])
+			if err != nil {
+				return nil, err
+			}
+			h.set(k, o)
+		}
+		return h, nil
 	}
 
 	return fromGoReflectValue(reflect.ValueOf(v))
@@ -167,6 +317,8 @@ func fromGoValue(v any) (object, error) {
 func fromGoReflectValue(resVal reflect.Value) (object, error) {
 	if !resVal.IsValid() {
 		return nil, nil
+	} else if o, ok := fromGoTimeValue(resVal); ok {
+		return o, nil
 	}
 
 	switch resVal.Kind() {
@@ -174,12 +326,24 @@ func fromGoReflectValue(resVal reflect.Value) (object, error) {
 		return listableProxyObject{v: resVal, orig: resVal}, nil
 	case reflect.Struct:
 		return newStructProxyObject(resVal, resVal), nil
+	case reflect.Map:
+		if resVal.Type().Key().Kind() == reflect.String {
+			return mapProxyObject{v: resVal, orig: resVal}, nil
+		}
 	case reflect.Pointer:
+		if o, ok := fromGoTimeValue(resVal.Elem()); ok {
+			return o, nil
+		}
+
 		switch resVal.Elem().Kind() {
 		case reflect.Slice:
 			return listableProxyObject{v: resVal.Elem(), orig: resVal}, nil
 		case reflect.Struct:
 			return newStructProxyObject(resVal.Elem(), resVal), nil
+		case reflect.Map:
+			if resVal.Elem().Type().Key().Kind() == reflect.String {
+				return mapProxyObject{v: resVal.Elem(), orig: resVal}, nil
+			}
 		}
 
 		return fromGoReflectValue(resVal.Elem())
@@ -188,6 +352,40 @@ func fromGoReflectValue(resVal reflect.Value) (object, error) {
 	return proxyObject{resVal.Interface()}, nil
 }
 
+// fromGoReflectElem converts an element reached through a proxy object, such as a slice item,
+// map value or struct field. Compound values are kept as reflect values so that they remain
+// addressable, allowing writes to go through to the underlying Go value.
+func fromGoReflectElem(v reflect.Value) object {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+
+	var (
+		o   object
+		err error
+	)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Struct, reflect.Map, reflect.Pointer:
+		if v.Type() != opaqueObjectType {
+			o, err = fromGoReflectValue(v)
+			break
+		}
+		fallthrough
+	default:
+		o, err = fromGoValue(v.Interface())
+	}
+	if err != nil {
+		return nil
+	}
+	return o
+}
+
 type macroArgs struct {
 	eval     evaluator
 	ec       *evalCtx
@@ -277,6 +475,15 @@ type invocationArgs struct {
 	ec     *evalCtx
 	args   []object
 	kwargs map[string]*listObject
+
+	// switches is each switch in the order they were given, including those given more than once
+	switches []switchArg
+}
+
+// switchArg is a switch of an invocation, along with its arguments.
+type switchArg struct {
+	name string
+	args *listObject
 }
 
 func (ia invocationArgs) expectArgn(x int) error {
@@ -297,6 +504,22 @@ func (ia invocationArgs) stringArg(i int) (string, error) {
 	return s.String(), nil
 }
 
+func (ia invocationArgs) intArg(i int) (int, error) {
+	if len(ia.args) <= i {
+		return 0, errors.New("expected at least " + strconv.Itoa(i+1) + " args")
+	}
+	n, ok := ia.args[i].(intObject)
+	if !ok {
+		return 0, errors.New("expected an int arg")
+	}
+	return int(n), nil
+}
+
+func (ia invocationArgs) hasSwitch(name string) bool {
+	_, ok := ia.kwargs[name]
+	return ok
+}
+
 func (ia invocationArgs) invokableArg(i int) (invokable, error) {
 	if len(ia.args) < i {
 		return nil, errors.New("expected at least " + strconv.Itoa(i) + " args")
@@ -315,6 +538,33 @@ func (ia invocationArgs) invokableArg(i int) (invokable, error) {
 	return nil, errors.New("expected an invokable arg")
 }
 
+// invokable wraps inv as an Invokable which will be invoked within the context of ia.
+func (ia invocationArgs) invokable(inv invokable) Invokable {
+	return Invokable{
+		inv:  inv,
+		eval: ia.eval,
+		inst: ia.inst,
+		ec:   ia.ec,
+	}
+}
+
+// toGoValue converts obj to a Go value, with invokable objects returned as an Invokable.
+func (ia invocationArgs) toGoValue(obj object) (any, error) {
+	goRes, ok := toGoValueWith(obj, ia.orderedHashes())
+	if !ok {
+		if inv, isInv := obj.(invokable); isInv {
+			return ia.invokable(inv), nil
+		}
+		return nil, errors.New("result not convertable to go")
+	}
+	return goRes, nil
+}
+
+// orderedHashes returns true if hashes are to be converted to an *OrderedHash.
+func (ia invocationArgs) orderedHashes() bool {
+	return ia.inst != nil && ia.inst.orderedHashes
+}
+
 func (ia invocationArgs) fork(args []object) invocationArgs {
 	return invocationArgs{
 		eval:   ia.eval,
@@ -330,11 +580,12 @@ func (ia invocationArgs) shift(i int) invocationArgs {
 		return ia
 	}
 	return invocationArgs{
-		eval:   ia.eval,
-		inst:   ia.inst,
-		ec:     ia.ec,
-		args:   ia.args[i:],
-		kwargs: ia.kwargs,
+		eval:     ia.eval,
+		inst:     ia.inst,
+		ec:       ia.ec,
+		args:     ia.args[i:],
+		kwargs:   ia.kwargs,
+		switches: ia.switches,
 	}
 }
 
@@ -423,11 +674,7 @@ func (p listableProxyObject) Len() int {
 }
 
 func (p listableProxyObject) Index(i int) object {
-	e, err := fromGoValue(p.v.Index(i).Interface())
-	if err != nil {
-		return nil
-	}
-	return e
+	return fromGoReflectElem(p.v.Index(i))
 }
 
 type structProxyObject struct {
@@ -462,38 +709,90 @@ func (s structProxyObject) Value(k string) object {
 		return nil
 	}
 
-	if f.Kind() == reflect.Ptr {
-		if f.IsNil() {
-			return nil
+	return fromGoReflectElem(f)
+}
+
+func (s structProxyObject) Each(fn func(k string, v object) error) error {
+	for _, f := range s.vf {
+		if err := fn(f.Name, fromGoReflectElem(s.v.FieldByName(f.Name))); err != nil {
+			return err
 		}
-		f = f.Elem()
+	}
+	return nil
+}
+
+func (s structProxyObject) setValue(ia invocationArgs, k string, val object) error {
+	f := s.v.FieldByName(k)
+	if !f.IsValid() {
+		return fmt.Errorf("no field '%v' on %v", k, s.v.Type())
+	} else if !f.CanSet() {
+		return fmt.Errorf("field '%v' on %v is not settable", k, s.v.Type())
 	}
 
-	e, err := fromGoValue(f.Interface())
+	rv, err := ia.toGoReflectValue(val, f.Type())
 	if err != nil {
+		return fmt.Errorf("field '%v': %w", k, err)
+	}
+	f.Set(rv)
+	return nil
+}
+
+type mapProxyObject struct {
+	v    reflect.Value
+	orig reflect.Value
+}
+
+func (p mapProxyObject) String() string {
+	return fmt.Sprintf("mapProxyObject{%v}", p.v.Type())
+}
+
+func (p mapProxyObject) Truthy() bool {
+	return p.v.Len() > 0
+}
+
+func (p mapProxyObject) Len() int {
+	return p.v.Len()
+}
+
+func (p mapProxyObject) Value(k string) object {
+	e := p.v.MapIndex(reflect.ValueOf(k).Convert(p.v.Type().Key()))
+	if !e.IsValid() {
 		return nil
 	}
-	return e
+	return fromGoReflectElem(e)
 }
 
-func (s structProxyObject) Each(fn func(k string, v object) error) error {
-	for _, f := range s.vf {
-		v, err := fromGoValue(s.v.FieldByName(f.Name).Interface())
-		if err != nil {
-			v = nil
-		}
+func (p mapProxyObject) Each(fn func(k string, v object) error) error {
+	keys := p.v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
 
-		if err := fn(f.Name, v); err != nil {
+	for _, k := range keys {
+		if err := fn(k.String(), fromGoReflectElem(p.v.MapIndex(k))); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (p mapProxyObject) setValue(ia invocationArgs, k string, val object) error {
+	if p.v.IsNil() {
+		return errors.New("cannot set key of nil map")
+	}
+
+	rv, err := ia.toGoReflectValue(val, p.v.Type().Elem())
+	if err != nil {
+		return fmt.Errorf("key '%v': %w", k, err)
+	}
+	p.v.SetMapIndex(reflect.ValueOf(k).Convert(p.v.Type().Key()), rv)
+	return nil
+}
+
 type OpaqueObject struct {
 	v any
 }
 
+var opaqueObjectType = reflect.TypeOf(OpaqueObject{})
+
 func Opaque(v any) OpaqueObject {
 	return OpaqueObject{v: v}
 }
@@ -527,3 +826,13 @@ func (e errReturn) Error() string {
 }
 
 var ErrHalt = errors.New("halt")
+
+// ExitError is returned from evaluation when a script calls exit. Code is the exit code
+// passed to exit, which is 0 if none was given.
+type ExitError struct {
+	Code int
+}
+
+func (e ExitError) Error() string {
+	return "exit status " + strconv.Itoa(e.Code)
+}
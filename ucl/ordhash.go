@@ -0,0 +1,128 @@
+package ucl
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// OrderedHash is a hash which preserves the order of its keys. Instances created with
+// WithOrderedHashes return hashes as an *OrderedHash rather than a map[string]any, so that
+// the order of the keys, as written in a hash literal or as added with set-at, is retained.
+// An *OrderedHash passed to the instance is converted to a hash with the same key order.
+type OrderedHash struct {
+	keys []string
+	vals map[string]any
+}
+
+var orderedHashType = reflect.TypeOf(OrderedHash{})
+
+// NewOrderedHash returns a new, empty OrderedHash.
+func NewOrderedHash() *OrderedHash {
+	return &OrderedHash{vals: make(map[string]any)}
+}
+
+// Set sets the value of k. New keys are added to the end of the hash, while existing keys
+// keep their position.
+func (h *OrderedHash) Set(k string, v any) {
+	if h.vals == nil {
+		h.vals = make(map[string]any)
+	}
+	if _, ok := h.vals[k]; !ok {
+		h.keys = append(h.keys, k)
+	}
+	h.vals[k] = v
+}
+
+// Get returns the value of k, and whether the key is present.
+func (h OrderedHash) Get(k string) (any, bool) {
+	v, ok := h.vals[k]
+	return v, ok
+}
+
+// Keys returns the keys of the hash in order.
+func (h OrderedHash) Keys() []string {
+	return append([]string(nil), h.keys...)
+}
+
+func (h OrderedHash) Len() int {
+	return len(h.keys)
+}
+
+// Map returns the keys and values of the hash as a map.
+func (h OrderedHash) Map() map[string]any {
+	m := make(map[string]any, len(h.keys))
+	for k, v := range h.vals {
+		m[k] = v
+	}
+	return m
+}
+
+func (h OrderedHash) String() string {
+	sb := strings.Builder{}
+	sb.WriteString("map[")
+	for i, k := range h.keys {
+		if i > 0 {
+			sb.WriteRune(' ')
+		}
+		sb.WriteString(k)
+		sb.WriteRune(':')
+		sb.WriteString(displayCell(reflect.ValueOf(h.vals[k])))
+	}
+	sb.WriteRune(']')
+	return sb.String()
+}
+
+// MarshalJSON encodes the hash as a JSON object with the keys in order.
+func (h OrderedHash) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteRune('{')
+	for i, k := range h.keys {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
+
+		kb, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		vb, err := json.Marshal(h.vals[k])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(kb)
+		buf.WriteRune(':')
+		buf.Write(vb)
+	}
+	buf.WriteRune('}')
+	return buf.Bytes(), nil
+}
+
+// WithOrderedHashes returns hashes as an *OrderedHash, rather than a map[string]any, when
+// converting results to Go values, such as those returned from Eval or bound to an any
+// argument of a builtin.
+func WithOrderedHashes() InstOption {
+	return func(i *Inst) {
+		i.orderedHashes = true
+	}
+}
+
+// OrderedHashes returns true if the instance was created with WithOrderedHashes. Builtins
+// which build hashes from ordered sources can use it to decide whether to return an
+// *OrderedHash.
+func (ca CallArgs) OrderedHashes() bool {
+	return ca.args.orderedHashes()
+}
+
+// orderedHashOf returns the OrderedHash of rv, which may be an OrderedHash or a pointer to one.
+func orderedHashOf(rv reflect.Value) (OrderedHash, bool) {
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || rv.Type() != orderedHashType {
+		return OrderedHash{}, false
+	}
+	return rv.Interface().(OrderedHash), true
+}
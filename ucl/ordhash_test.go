@@ -0,0 +1,139 @@
+package ucl_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"ucl.lmika.dev/ucl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInst_HashOrder(t *testing.T) {
+	tests := []struct {
+		desc string
+		expr string
+		want any
+	}{
+		{desc: "literal order", expr: `keys [z:1 a:2 m:3]`, want: []any{"z", "a", "m"}},
+		{desc: "set-at adds to end", expr: `set h [z:1 a:2] ; set-at $h "b" 3 ; keys $h`, want: []any{"z", "a", "b"}},
+		{desc: "set-at existing keeps position", expr: `set h [z:1 a:2] ; set-at $h "z" 3 ; keys $h`, want: []any{"z", "a"}},
+		{desc: "set-at is seen through other references", expr: `set h [a:1] ; set g $h ; set-at $h "b" 2 ; keys $g`, want: []any{"a", "b"}},
+		{desc: "del keeps order", expr: `del [z:1 a:2 m:3] "a" | keys`, want: []any{"z", "m"}},
+		{desc: "merge keeps first position", expr: `merge [z:1 a:2] [m:3 z:4] | keys`, want: []any{"z", "a", "m"}},
+		{desc: "filter keeps order", expr: `filter [z:1 a:2 m:3] { |k v| eq $k "a" | not } | keys`, want: []any{"z", "m"}},
+		{desc: "go maps in key order", expr: `goMap | mapValues { |v| $v } | keys`, want: []any{"a", "b", "c"}},
+		{desc: "ordered go hash", expr: `goOrderedHash | keys`, want: []any{"y", "x"}},
+		{desc: "repr", expr: `repr [z:1 a:[y:2 b:3]]`, want: `[z:1 a:[y:2 b:3]]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			inst := ucl.New()
+			inst.SetBuiltin("goMap", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+				return map[string]any{"c": 1, "a": 2, "b": 3}, nil
+			})
+			inst.SetBuiltin("goOrderedHash", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+				h := ucl.NewOrderedHash()
+				h.Set("y", 1)
+				h.Set("x", 2)
+				return h, nil
+			})
+			inst.SetBuiltin("not", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+				var b bool
+				err := args.Bind(&b)
+				return !b, err
+			})
+
+			res, err := inst.Eval(context.Background(), tt.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestWithOrderedHashes(t *testing.T) {
+	t.Run("eval returns ordered hashes", func(t *testing.T) {
+		inst := ucl.New(ucl.WithOrderedHashes())
+		res, err := inst.Eval(context.Background(), `[z:1 a:[y:2 b:3] m:[[k:1]]]`)
+		assert.NoError(t, err)
+
+		h, ok := res.(*ucl.OrderedHash)
+		assert.True(t, ok)
+		assert.Equal(t, []string{"z", "a", "m"}, h.Keys())
+
+		nested, _ := h.Get("a")
+		assert.Equal(t, []string{"y", "b"}, nested.(*ucl.OrderedHash).Keys())
+
+		list, _ := h.Get("m")
+		assert.IsType(t, &ucl.OrderedHash{}, list.([]any)[0])
+	})
+
+	t.Run("without option returns maps", func(t *testing.T) {
+		inst := ucl.New()
+		res, err := inst.Eval(context.Background(), `[z:1 a:2]`)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"z": 1, "a": 2}, res)
+	})
+
+	t.Run("bound to builtin args", func(t *testing.T) {
+		inst := ucl.New(ucl.WithOrderedHashes())
+		inst.SetBuiltin("toJSON", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+			var v any
+			if err := args.Bind(&v); err != nil {
+				return nil, err
+			}
+			bts, err := json.Marshal(v)
+			return string(bts), err
+		})
+
+		res, err := inst.Eval(context.Background(), `toJSON [z:1 a:[y:"two" b:()] m:[1 2]]`)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"z":1,"a":{"y":"two","b":null},"m":[1,2]}`, res)
+	})
+
+	t.Run("reported to builtins", func(t *testing.T) {
+		for _, ordered := range []bool{false, true} {
+			var opts []ucl.InstOption
+			if ordered {
+				opts = append(opts, ucl.WithOrderedHashes())
+			}
+
+			inst := ucl.New(opts...)
+			inst.SetBuiltin("ordered", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+				return args.OrderedHashes(), nil
+			})
+
+			res, err := inst.Eval(context.Background(), `ordered`)
+			assert.NoError(t, err)
+			assert.Equal(t, ordered, res)
+		}
+	})
+
+	t.Run("displayed in order", func(t *testing.T) {
+		tests := []struct {
+			desc      string
+			displayer ucl.Displayer
+			want      string
+		}{
+			{desc: "plain", displayer: ucl.PlainDisplayer{}, want: "map[z:1 a:2]\n"},
+			{desc: "table", displayer: ucl.TableDisplayer{}, want: "z  1\na  2\n"},
+			{desc: "tree", displayer: ucl.TreeDisplayer{}, want: "z: 1\na: 2\n"},
+			{desc: "json", displayer: ucl.JSONDisplayer{}, want: "{\"z\":1,\"a\":2}\n"},
+			{desc: "repr", displayer: ucl.ReprDisplayer{}, want: "[z:1 a:2]\n"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.desc, func(t *testing.T) {
+				outW := bytes.NewBuffer(nil)
+				inst := ucl.New(ucl.WithOut(outW), ucl.WithDisplayer(tt.displayer), ucl.WithOrderedHashes())
+
+				err := ucl.EvalAndDisplay(context.Background(), inst, `[z:1 a:2]`)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, outW.String())
+			})
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package ucl
+
+import (
+	"math/rand"
+	"time"
+)
+
+// WithRandSeed seeds the random number generator of the instance, such as used by math:rand,
+// so that the same sequence of numbers is produced on each run. By default, the generator
+// is seeded from the current time.
+func WithRandSeed(seed int64) InstOption {
+	return func(i *Inst) {
+		i.rand = rand.New(rand.NewSource(seed))
+	}
+}
+
+// Rand returns the random number generator of the instance. It is not safe for concurrent
+// use.
+func (ca CallArgs) Rand() *rand.Rand {
+	if ca.args.inst == nil {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	if ca.args.inst.rand == nil {
+		ca.args.inst.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return ca.args.inst.rand
+}
@@ -0,0 +1,145 @@
+package ucl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Format returns v rendered as UCL source, such that evaluating the result produces an
+// equivalent value. Strings are quoted, lists and hashes are written as literals, and blocks
+// and procs are written as their source. Times, durations, and floats which are infinite or
+// NaN are written as quoted strings, which bind back to time.Time, time.Duration and float
+// arguments of Go builtins. Other Go values which have no literal form, such as opaque values,
+// are written as their string form.
+func (inst *Inst) Format(v any) (string, error) {
+	obj, err := fromGoValue(v)
+	if err != nil {
+		return "", err
+	}
+	return repr(obj), nil
+}
+
+// ReprDisplayer displays strings as is, and other values as UCL source. Nil is not displayed.
+type ReprDisplayer struct{}
+
+func (ReprDisplayer) Display(w io.Writer, v any) error {
+	var err error
+	switch t := v.(type) {
+	case nil:
+	case Displayable:
+		err = t.Display(w)
+	case string:
+		_, err = fmt.Fprintln(w, t)
+	default:
+		var obj object
+		if obj, err = fromGoValue(v); err == nil {
+			_, err = fmt.Fprintln(w, repr(obj))
+		}
+	}
+	return err
+}
+
+func repr(obj object) string {
+	sb := strings.Builder{}
+	writeRepr(&sb, obj)
+	return sb.String()
+}
+
+func writeRepr(sb *strings.Builder, obj object) {
+	switch t := obj.(type) {
+	case nil:
+		sb.WriteString("()")
+	case strObject:
+		sb.WriteString(strconv.Quote(string(t)))
+	case intObject:
+		sb.WriteString(strconv.Itoa(int(t)))
+	case floatObject:
+		// Infinities and NaN have no literal form, so they are written as strings
+		if f := float64(t); math.IsInf(f, 0) || math.IsNaN(f) {
+			sb.WriteString(strconv.Quote(t.String()))
+			return
+		}
+
+		s := t.String()
+		sb.WriteString(s)
+		if !strings.Contains(s, ".") {
+			sb.WriteString(".0")
+		}
+	case boolObject:
+		sb.WriteString(t.String())
+	case timeObject, durationObject:
+		sb.WriteString(strconv.Quote(t.String()))
+	case blockObject:
+		sb.WriteString(t.block.source())
+	case procObject:
+		sb.WriteString("proc ")
+		sb.WriteString(t.block.source())
+	case listable:
+		sb.WriteRune('[')
+		for i := 0; i < t.Len(); i++ {
+			if i > 0 {
+				sb.WriteRune(' ')
+			}
+			writeRepr(sb, t.Index(i))
+		}
+		sb.WriteRune(']')
+	case hashable:
+		if t.Len() == 0 {
+			sb.WriteString("[:]")
+			return
+		}
+
+		sb.WriteRune('[')
+		first := true
+		_ = t.Each(func(k string, v object) error {
+			if !first {
+				sb.WriteRune(' ')
+			}
+			first = false
+
+			if identPattern.MatchString(k) {
+				sb.WriteString(k)
+			} else {
+				sb.WriteString(strconv.Quote(k))
+			}
+			sb.WriteRune(':')
+			writeRepr(sb, v)
+			return nil
+		})
+		sb.WriteRune(']')
+	default:
+		sb.WriteString(t.String())
+	}
+}
+
+func reprBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(1); err != nil {
+		return nil, err
+	}
+	return strObject(repr(args.args[0])), nil
+}
+
+// inspectBuiltin writes the type and source form of the argument to the output, and returns
+// the argument unchanged so that it can be used within a pipeline.
+func inspectBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	if err := args.expectArgn(1); err != nil {
+		return nil, err
+	}
+
+	if _, err := fmt.Fprintf(args.inst.Out(), "%v: %v\n", typeName(args.args[0]), repr(args.args[0])); err != nil {
+		return nil, err
+	}
+	return args.args[0], nil
+}
+
+func trueBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	return boolObject(true), nil
+}
+
+func falseBuiltin(ctx context.Context, args invocationArgs) (object, error) {
+	return boolObject(false), nil
+}
@@ -0,0 +1,152 @@
+package ucl_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"ucl.lmika.dev/ucl"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltins_Repr(t *testing.T) {
+	tests := []struct {
+		desc string
+		expr string
+		want string
+	}{
+		{desc: "string", expr: `repr "hello"`, want: `"hello"`},
+		{desc: "string with escapes", expr: `repr "say \"hi\"\n\tthere\\"`, want: `"say \"hi\"\n\tthere\\"`},
+		{desc: "int", expr: `repr -123`, want: `-123`},
+		{desc: "floats", expr: `repr [1.5 -0.25 2.0]`, want: `[1.5 -0.25 2.0]`},
+		{desc: "bools", expr: `repr [(true) (false)]`, want: `[(true) (false)]`},
+		{desc: "nil", expr: `repr ()`, want: `()`},
+		{desc: "list", expr: `repr [1 "two" [3]]`, want: `[1 "two" [3]]`},
+		{desc: "empty list", expr: `repr []`, want: `[]`},
+		{desc: "hash", expr: `repr [b:2 a:"one" "with space":[c:()]]`, want: `[b:2 a:"one" "with space":[c:()]]`},
+		{desc: "empty hash", expr: `repr [:]`, want: `[:]`},
+		{desc: "block", expr: `repr { |x|  echo $x   }`, want: `{ |x|  echo $x   }`},
+		{desc: "multi-line block", expr: "repr {\n  echo \"a\"  # comment\n}", want: "{\n  echo \"a\"  # comment\n}"},
+		{desc: "proc", expr: `repr (proc { |a b| add $a $b })`, want: `proc { |a b| add $a $b }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ctx := context.Background()
+
+			inst := ucl.New()
+			res, err := inst.Eval(ctx, tt.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		ctx := context.Background()
+
+		for _, expr := range []string{
+			`"hello \"world\""`,
+			`[1 "two" [three:3 "fo ur":[4]] [:] [] (true) (false) ()]`,
+			`call (proc { |a b| add $a $b }) 2 3`,
+		} {
+			inst := ucl.New()
+
+			want, err := inst.Eval(ctx, expr)
+			assert.NoError(t, err)
+
+			src, err := inst.Format(want)
+			assert.NoError(t, err)
+
+			got, err := inst.Eval(ctx, src)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got, "source: %v", src)
+		}
+	})
+}
+
+func TestBuiltins_Inspect(t *testing.T) {
+	ctx := context.Background()
+	outW := bytes.NewBuffer(nil)
+
+	inst := ucl.New(ucl.WithOut(outW))
+	res, err := inst.Eval(ctx, `inspect [a:1] | keys | inspect | len`)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res)
+	assert.Equal(t, "hash: [a:1]\nlist: [\"a\"]\n", outW.String())
+}
+
+func TestInst_Format(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+
+	tests := []struct {
+		desc string
+		val  any
+		want string
+	}{
+		{desc: "nil", val: nil, want: `()`},
+		{desc: "string", val: "a \"b\"", want: `"a \"b\""`},
+		{desc: "bool", val: true, want: `(true)`},
+		{desc: "slice", val: []any{1, "2", []string{"3"}}, want: `[1 "2" ["3"]]`},
+		{desc: "map", val: map[string]any{"b": 1, "a": []int{}}, want: `[a:[] b:1]`},
+		{desc: "struct", val: point{X: 1, Y: 2}, want: `[X:1 Y:2]`},
+		{desc: "struct pointer", val: &point{X: 3}, want: `[X:3 Y:0]`},
+		{desc: "float", val: 3.0, want: `3.0`},
+		{desc: "large float", val: 1e21, want: `1000000000000000000000.0`},
+		{desc: "infinite floats", val: []any{math.Inf(1), math.Inf(-1), math.NaN()}, want: `["+Inf" "-Inf" "NaN"]`},
+		{desc: "time", val: time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC), want: `"2024-01-02T03:04:05.0000006Z"`},
+		{desc: "duration", val: 5*time.Minute + 30*time.Second, want: `"5m30s"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := ucl.New().Format(tt.val)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("round trip through Go builtins", func(t *testing.T) {
+		ctx := context.Background()
+
+		inst := ucl.New()
+		inst.SetFunc("sameTime", func(t time.Time) time.Time { return t })
+		inst.SetFunc("sameDuration", func(d time.Duration) time.Duration { return d })
+		inst.SetFunc("sameFloat", func(f float64) float64 { return f })
+
+		for _, tt := range []struct {
+			fn  string
+			val any
+		}{
+			{fn: "sameTime", val: time.Date(2024, 1, 2, 3, 4, 5, 600, time.FixedZone("", 10*60*60))},
+			{fn: "sameDuration", val: 90 * time.Minute},
+			{fn: "sameFloat", val: 1.5},
+			{fn: "sameFloat", val: math.Inf(1)},
+			{fn: "sameFloat", val: math.Inf(-1)},
+			{fn: "sameFloat", val: math.NaN()},
+		} {
+			src, err := inst.Format(tt.val)
+			assert.NoError(t, err)
+
+			got, err := inst.Eval(ctx, tt.fn+" "+src)
+			assert.NoError(t, err, "source: %v", src)
+
+			switch want := tt.val.(type) {
+			case time.Time:
+				assert.True(t, want.Equal(got.(time.Time)), "source: %v", src)
+			case float64:
+				if math.IsNaN(want) {
+					assert.True(t, math.IsNaN(got.(float64)), "source: %v", src)
+				} else {
+					assert.Equal(t, want, got, "source: %v", src)
+				}
+			default:
+				assert.Equal(t, want, got, "source: %v", src)
+			}
+		}
+	})
+}
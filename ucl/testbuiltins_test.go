@@ -33,7 +33,7 @@ func WithTestBuiltin() InstOption {
 		}))
 
 		i.rootEC.addCmd("list", invokableFunc(func(ctx context.Context, args invocationArgs) (object, error) {
-			return listObject(args.args), nil
+			return &listObject{elems: args.args}, nil
 		}))
 
 		i.rootEC.addCmd("joinpipe", invokableFunc(func(ctx context.Context, args invocationArgs) (object, error) {
@@ -68,6 +68,9 @@ func TestBuiltins_Echo(t *testing.T) {
 		{desc: "no args", expr: `echo`, want: "\n"},
 		{desc: "single arg", expr: `echo "hello"`, want: "hello\n"},
 		{desc: "dual args", expr: `echo "hello " "world"`, want: "hello world\n"},
+		{desc: "list", expr: `echo ["a" "b"]`, want: "[a b]\n"},
+		{desc: "hash", expr: `echo [b:"two" a:1 c:()]`, want: "[b:two a:1 c:()]\n"},
+		{desc: "bools", expr: `echo (true) (false)`, want: "(true)(false)\n"},
 		{desc: "multi-line 1", expr: `
 			echo "Hello"
 			echo "world"
@@ -508,6 +511,54 @@ func TestBuiltins_Return(t *testing.T) {
 	}
 }
 
+func TestBuiltins_Exit(t *testing.T) {
+	tests := []struct {
+		desc     string
+		expr     string
+		want     string
+		wantCode int
+		wantErr  string
+	}{
+		{desc: "exit without code", expr: `
+			echo "a"
+			exit
+			echo "b"
+		`, want: "a\n"},
+		{desc: "exit with code", expr: `
+			echo "a"
+			exit 3
+		`, want: "a\n", wantCode: 3},
+		{desc: "exit from proc within loop", expr: `
+			proc check { |v|
+				if (eq $v "2") { exit 2 }
+				echo $v
+			}
+			foreach ["1" "2" "3"] { |v| check $v }
+		`, want: "1\n", wantCode: 2},
+		{desc: "exit with non-int", expr: `exit "bad"`, wantErr: "expected an int arg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ctx := context.Background()
+			outW := bytes.NewBuffer(nil)
+
+			inst := New(WithOut(outW), WithTestBuiltin())
+			_, err := inst.Eval(ctx, tt.expr)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			var exitErr ExitError
+			assert.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, tt.wantCode, exitErr.Code)
+			assert.Equal(t, tt.want, outW.String())
+		})
+	}
+}
+
 func TestBuiltins_Map(t *testing.T) {
 	tests := []struct {
 		desc string
@@ -823,3 +874,132 @@ func TestBuiltins_Reduce(t *testing.T) {
 		})
 	}
 }
+
+func TestBuiltins_SetAt(t *testing.T) {
+	type item struct {
+		Name  string
+		Count int
+	}
+
+	tests := []struct {
+		desc      string
+		expr      string
+		want      any
+		wantErr   bool
+		wantMap   map[string]int
+		wantSl    []string
+		wantSt    item
+		wantItems []item
+	}{
+		{desc: "set hash key", expr: `set h [a:1] ; set-at $h b 2 ; $h`, want: map[string]any{"a": 1, "b": 2}},
+		{desc: "set list index", expr: `set l [1 2 3] ; set-at $l 1 "two" ; $l`, want: []any{1, "two", 3}},
+		{desc: "set list out of range", expr: `set-at [1 2 3] 5 "two"`, wantErr: true},
+		{desc: "set go map", expr: `set-at (goMap) c 3`, want: 3, wantMap: map[string]int{"a": 1, "b": 2, "c": 3}},
+		{desc: "set go map bad type", expr: `set-at (goMap) c "three"`, wantErr: true, wantMap: map[string]int{"a": 1, "b": 2}},
+		{desc: "set go slice", expr: `set-at (goSlice) 0 "zero"`, want: "zero", wantSl: []string{"zero", "y"}},
+		{desc: "set go slice bad type", expr: `set-at (goSlice) 0 123`, wantErr: true, wantSl: []string{"x", "y"}},
+		{desc: "set go struct ptr field", expr: `set-at (goStructPtr) Count 12`, want: 12, wantSt: item{Name: "thing", Count: 12}},
+		{desc: "set go struct ptr missing field", expr: `set-at (goStructPtr) Missing 12`, wantErr: true, wantSt: item{Name: "thing"}},
+		{desc: "set go struct value field", expr: `set-at (goStruct) Count 12`, wantErr: true, wantSt: item{Name: "thing"}},
+		{desc: "set nested go struct field", expr: `goSliceOfStructs | index 1 | set-at Name "second"`, want: "second", wantItems: []item{{Name: "x"}, {Name: "second"}}},
+		{desc: "set string", expr: `set-at "abc" 1 "x"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ctx := context.Background()
+			outW := bytes.NewBuffer(nil)
+
+			goMap := map[string]int{"a": 1, "b": 2}
+			goSlice := []string{"x", "y"}
+			goItem := item{Name: "thing"}
+			goItems := []item{{Name: "x"}, {Name: "y"}}
+
+			inst := New(WithOut(outW), WithTestBuiltin())
+			inst.SetBuiltin("goMap", func(ctx context.Context, args CallArgs) (any, error) {
+				return goMap, nil
+			})
+			inst.SetBuiltin("goSlice", func(ctx context.Context, args CallArgs) (any, error) {
+				return goSlice, nil
+			})
+			inst.SetBuiltin("goStructPtr", func(ctx context.Context, args CallArgs) (any, error) {
+				return &goItem, nil
+			})
+			inst.SetBuiltin("goStruct", func(ctx context.Context, args CallArgs) (any, error) {
+				return goItem, nil
+			})
+			inst.SetBuiltin("goSliceOfStructs", func(ctx context.Context, args CallArgs) (any, error) {
+				return goItems, nil
+			})
+
+			res, err := inst.Eval(ctx, tt.expr)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+
+			if tt.wantMap != nil {
+				assert.Equal(t, tt.wantMap, goMap)
+			}
+			if tt.wantSl != nil {
+				assert.Equal(t, tt.wantSl, goSlice)
+			}
+			if tt.wantSt.Name != "" {
+				assert.Equal(t, tt.wantSt, goItem)
+			}
+			if tt.wantItems != nil {
+				assert.Equal(t, tt.wantItems, goItems)
+			}
+		})
+	}
+}
+
+func TestBuiltins_Append(t *testing.T) {
+	tests := []struct {
+		desc    string
+		expr    string
+		want    any
+		wantErr bool
+		wantSl  []int
+	}{
+		{desc: "append to list", expr: `append [1 2] 3 4`, want: []any{1, 2, 3, 4}},
+		{desc: "append to list via pipe", expr: `[1 2] | append 3`, want: []any{1, 2, 3}},
+		{desc: "append to nil", expr: `append () 1`, want: []any{1}},
+		{desc: "append does not modify list", expr: `set l [1 2] ; append $l 3 ; $l`, want: []any{1, 2}},
+		{desc: "append to go slice", expr: `append (goSlice) 3`, want: []int{1, 2, 3}, wantSl: []int{1, 2}},
+		{desc: "append to go slice ptr", expr: `append (goSlicePtr) 3 4 ; len (goSlicePtr)`, want: 4, wantSl: []int{1, 2, 3, 4}},
+		{desc: "append to go slice bad type", expr: `append (goSlice) "three"`, wantErr: true, wantSl: []int{1, 2}},
+		{desc: "append to hash", expr: `append [a:1] 3`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ctx := context.Background()
+			outW := bytes.NewBuffer(nil)
+
+			goSlice := []int{1, 2}
+
+			inst := New(WithOut(outW), WithTestBuiltin())
+			inst.SetBuiltin("goSlice", func(ctx context.Context, args CallArgs) (any, error) {
+				return goSlice, nil
+			})
+			inst.SetBuiltin("goSlicePtr", func(ctx context.Context, args CallArgs) (any, error) {
+				return &goSlice, nil
+			})
+
+			res, err := inst.Eval(ctx, tt.expr)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+
+			if tt.wantSl != nil {
+				assert.Equal(t, tt.wantSl, goSlice)
+			}
+		})
+	}
+}
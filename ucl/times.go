@@ -0,0 +1,65 @@
+package ucl
+
+import (
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// timeObject is a point in time. Go builtins returning a time.Time produce a timeObject, and
+// it binds back to a time.Time argument as is.
+type timeObject time.Time
+
+func (t timeObject) String() string {
+	return time.Time(t).Format(time.RFC3339Nano)
+}
+
+func (t timeObject) Truthy() bool {
+	return !time.Time(t).IsZero()
+}
+
+// durationObject is a length of time. Go builtins returning a time.Duration produce a
+// durationObject.
+type durationObject time.Duration
+
+func (d durationObject) String() string {
+	return time.Duration(d).String()
+}
+
+func (d durationObject) Truthy() bool {
+	return d != 0
+}
+
+// WithClock sets the function used to get the current time, such as by time:now. This can
+// be used to fix the time for tests. The default is time.Now.
+func WithClock(now func() time.Time) InstOption {
+	return func(i *Inst) {
+		i.clock = now
+	}
+}
+
+// Now returns the current time, as given by the clock of the instance.
+func (ca CallArgs) Now() time.Time {
+	if ca.args.inst != nil && ca.args.inst.clock != nil {
+		return ca.args.inst.clock()
+	}
+	return time.Now()
+}
+
+// fromGoTimeValue returns the time object for rv if it is a time.Time or time.Duration.
+func fromGoTimeValue(rv reflect.Value) (object, bool) {
+	if !rv.IsValid() {
+		return nil, false
+	}
+
+	switch rv.Type() {
+	case timeType:
+		// The monotonic clock reading is dropped, so that times display and compare by
+		// their wall clock
+		return timeObject(rv.Interface().(time.Time).Round(0)), true
+	case durationType:
+		return durationObject(rv.Int()), true
+	}
+	return nil, false
+}
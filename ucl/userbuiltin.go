@@ -3,9 +3,13 @@ package ucl
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"os"
 	"reflect"
+	"sort"
 
-	"github.com/lmika/gopkgs/fp/slices"
+	"github.com/lmika/gopkgs/fp/maps"
 )
 
 type BuiltinHandler func(ctx context.Context, args CallArgs) (any, error)
@@ -13,24 +17,29 @@ type BuiltinHandler func(ctx context.Context, args CallArgs) (any, error)
 type MissingBuiltinHandler func(ctx context.Context, name string, args CallArgs) (any, error)
 
 type CallArgs struct {
-	args invocationArgs
+	args    invocationArgs
+	shifted int
 }
 
 func (ca *CallArgs) NArgs() int {
 	return len(ca.args.args)
 }
 
+// Bind binds the next positional arguments to the pointers in vars, then shifts them off.
+// Values are converted to the pointed to type, which can be any of the basic Go types, slices,
+// maps with string keys, structs populated from hashes, time.Duration, or types implementing
+// encoding.TextUnmarshaler. Binding to a *string will accept any non-nil argument.
 func (ca *CallArgs) Bind(vars ...interface{}) error {
 	if len(ca.args.args) < len(vars) {
-		return errors.New("wrong number of arguments")
+		return fmt.Errorf("wrong number of arguments: expected at least %d but was %d", ca.shifted+len(vars), ca.shifted+len(ca.args.args))
 	}
 
 	for i, v := range vars {
 		if err := ca.bindArg(v, ca.args.args[i]); err != nil {
-			return err
+			return fmt.Errorf("arg %d: %w", ca.shifted+i, err)
 		}
 	}
-	ca.args = ca.args.shift(len(vars))
+	ca.Shift(len(vars))
 	return nil
 }
 
@@ -40,7 +49,7 @@ func (ca *CallArgs) CanBind(vars ...interface{}) bool {
 	}
 
 	for i, v := range vars {
-		if !canBindArg(v, ca.args.args[i]) {
+		if !ca.canBindArg(v, ca.args.args[i]) {
 			return false
 		}
 	}
@@ -48,7 +57,11 @@ func (ca *CallArgs) CanBind(vars ...interface{}) bool {
 }
 
 func (ca *CallArgs) Shift(n int) {
+	if n > len(ca.args.args) {
+		return
+	}
 	ca.args = ca.args.shift(n)
+	ca.shifted += n
 }
 
 func (ca CallArgs) IsTopLevel() bool {
@@ -64,17 +77,117 @@ func (ca CallArgs) HasSwitch(name string) bool {
 	return ok
 }
 
+// SwitchNames returns the names of the switches of the invocation, without the leading "-", in
+// the order they were first given. A switch given more than once is only included once. Use
+// OrderedSwitches to get each time a switch was given.
+func (ca CallArgs) SwitchNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, sw := range ca.args.switches {
+		if !seen[sw.name] {
+			seen[sw.name] = true
+			names = append(names, sw.name)
+		}
+	}
+	return names
+}
+
+// SwitchArgs returns the arguments of the switch as Go values. Nil is returned if the switch
+// was not given. If the switch was given more than once, the arguments of the last one are
+// returned.
+func (ca CallArgs) SwitchArgs(name string) []any {
+	vars, ok := ca.args.kwargs[name]
+	if !ok {
+		return nil
+	}
+	return ca.switchGoValues(vars)
+}
+
+// SwitchArg is a switch of an invocation, as returned by OrderedSwitches.
+type SwitchArg struct {
+	// Name is the name of the switch, without the leading "-"
+	Name string
+
+	// Args is the arguments of the switch as Go values
+	Args []any
+}
+
+// OrderedSwitches returns each switch of the invocation in the order they were given. Unlike
+// SwitchNames and SwitchArgs, a switch given more than once is returned each time, along with
+// the arguments given to it that time.
+func (ca CallArgs) OrderedSwitches() []SwitchArg {
+	res := make([]SwitchArg, len(ca.args.switches))
+	for i, sw := range ca.args.switches {
+		res[i] = SwitchArg{Name: sw.name, Args: ca.switchGoValues(sw.args)}
+	}
+	return res
+}
+
+func (ca CallArgs) switchGoValues(vars *listObject) []any {
+	res := make([]any, 0, vars.Len())
+	for _, v := range vars.elems {
+		gv, err := ca.args.toGoValue(v)
+		if err != nil {
+			gv = v.String()
+		}
+		res = append(res, gv)
+	}
+	return res
+}
+
+// Out returns the writer of the instance that output is to be written to.
+func (ca CallArgs) Out() io.Writer {
+	if ca.args.inst == nil {
+		return os.Stdout
+	}
+	return ca.args.inst.Out()
+}
+
+// InstState returns the value held by the instance for key, calling newState to create it if
+// the instance does not have one. This allows a module to keep state, such as a cache, for
+// each instance it is added to. Key should be a value of an unexported type of the module.
+func (ca CallArgs) InstState(key any, newState func() any) any {
+	inst := ca.args.inst
+	if inst == nil {
+		return newState()
+	}
+
+	inst.stateMu.Lock()
+	defer inst.stateMu.Unlock()
+
+	if v, ok := inst.state[key]; ok {
+		return v
+	}
+	if inst.state == nil {
+		inst.state = make(map[any]any)
+	}
+	v := newState()
+	inst.state[key] = v
+	return v
+}
+
 func (ca CallArgs) BindSwitch(name string, val interface{}) error {
 	if ca.args.kwargs == nil {
 		return nil
 	}
 
 	vars, ok := ca.args.kwargs[name]
-	if !ok || len(*vars) != 1 {
+	if !ok {
+		return nil
+	}
+
+	// A switch without a value binds to a bool as true
+	if b, isBool := val.(*bool); isBool && vars.Len() == 0 {
+		*b = true
 		return nil
+	} else if vars.Len() != 1 {
+		return fmt.Errorf("switch -%v: expected 1 arg but was %d", name, vars.Len())
 	}
 
-	return ca.bindArg(val, (*vars)[0])
+	if err := ca.bindArg(val, vars.Index(0)); err != nil {
+		return fmt.Errorf("switch -%v: %w", name, err)
+	}
+	return nil
 }
 
 func (inst *Inst) SetBuiltin(name string, fn BuiltinHandler) {
@@ -97,127 +210,38 @@ func (u userBuiltin) invoke(ctx context.Context, args invocationArgs) (object, e
 func (ca CallArgs) bindArg(v interface{}, arg object) error {
 	switch t := v.(type) {
 	case *interface{}:
-		*t, _ = toGoValue(arg)
-	case *Invokable:
-		i, ok := arg.(invokable)
-		if !ok {
-			return errors.New("exepected invokable")
-		}
-		*t = Invokable{
-			inv:  i,
-			eval: ca.args.eval,
-			inst: ca.args.inst,
-			ec:   ca.args.ec,
-		}
+		*t, _ = toGoValueWith(arg, ca.args.orderedHashes())
 		return nil
 	case *string:
-		*t = arg.String()
-	case *int:
-		if iArg, ok := arg.(intObject); ok {
-			*t = int(iArg)
+		if arg == nil {
+			*t = ""
 		} else {
-			return errors.New("invalid arg")
-		}
-	}
-
-	switch t := arg.(type) {
-	case OpaqueObject:
-		if v == nil {
-			return errors.New("opaque object not bindable to nil")
+			*t = arg.String()
 		}
-
-		vr := reflect.ValueOf(v)
-		tr := reflect.ValueOf(t.v)
-		if vr.Kind() != reflect.Pointer {
-			return errors.New("expected pointer for an opaque object bind")
-		}
-
-		if !tr.Type().AssignableTo(vr.Elem().Type()) {
-			return errors.New("opaque object not assignable to passed in value")
-		}
-
-		vr.Elem().Set(tr)
 		return nil
-	case proxyObject:
-		return bindProxyObject(v, reflect.ValueOf(t.p))
-	case listableProxyObject:
-		return bindProxyObject(v, t.v)
-	case structProxyObject:
-		return bindProxyObject(v, t.v)
 	}
 
-	return nil
-}
-
-func canBindArg(v interface{}, arg object) bool {
-	switch v.(type) {
-	case *string:
-		return true
-	case *int:
-		_, ok := arg.(intObject)
-		return ok
-	}
-
-	switch t := arg.(type) {
-	case OpaqueObject:
-		vr := reflect.ValueOf(v)
-		tr := reflect.ValueOf(t.v)
-		if vr.Kind() != reflect.Pointer {
-			return false
-		}
-
-		if !tr.Type().AssignableTo(vr.Elem().Type()) {
-			return false
-		}
-
-		return true
-	case proxyObject:
-		return canBindProxyObject(v, reflect.ValueOf(t.p))
-	case listableProxyObject:
-		return canBindProxyObject(v, t.v)
-	case structProxyObject:
-		return canBindProxyObject(v, t.v)
+	vr := reflect.ValueOf(v)
+	if vr.Kind() != reflect.Pointer || vr.IsNil() {
+		return errors.New("bind target must be a non-nil pointer")
 	}
 
-	return true
-}
-
-func bindProxyObject(v interface{}, r reflect.Value) error {
-	argValue := reflect.ValueOf(v)
-	if argValue.Kind() != reflect.Ptr {
-		return errors.New("v must be a pointer to a struct")
-	}
-
-	for {
-		if r.Type().AssignableTo(argValue.Elem().Type()) {
-			argValue.Elem().Set(r)
-			return nil
-		}
-		if r.Type().Kind() != reflect.Pointer {
-			return nil
-		}
-
-		r = r.Elem()
+	rv, err := ca.args.toGoReflectValue(arg, vr.Elem().Type())
+	if err != nil {
+		return err
 	}
+	vr.Elem().Set(rv)
+	return nil
 }
 
-func canBindProxyObject(v interface{}, r reflect.Value) bool {
-	argValue := reflect.ValueOf(v)
-	if argValue.Kind() != reflect.Ptr {
+func (ca CallArgs) canBindArg(v interface{}, arg object) bool {
+	vr := reflect.ValueOf(v)
+	if vr.Kind() != reflect.Pointer || vr.IsNil() {
 		return false
 	}
 
-	for {
-		if r.Type().AssignableTo(argValue.Elem().Type()) {
-			argValue.Elem().Set(r)
-			return true
-		}
-		if r.Type().Kind() != reflect.Pointer {
-			return true
-		}
-
-		r = r.Elem()
-	}
+	// Bind to a throwaway value so that v is left untouched
+	return ca.bindArg(reflect.New(vr.Elem().Type()).Interface(), arg) == nil
 }
 
 func (inst *Inst) missingHandlerInvokable(name string) missingHandlerInvokable {
@@ -238,6 +262,35 @@ func (m missingHandlerInvokable) invoke(ctx context.Context, args invocationArgs
 	return fromGoValue(v)
 }
 
+// Switches can be passed as an argument to Invokable.Invoke or Inst.Call to set the switches
+// of the invocation. A switch with a nil value is set without any arguments.
+type Switches map[string]any
+
+func (ia *invocationArgs) addSwitches(sw Switches) error {
+	if ia.kwargs == nil {
+		ia.kwargs = make(map[string]*listObject)
+	}
+
+	// Switches given as a map are ordered by name
+	names := maps.Keys(sw)
+	sort.Strings(names)
+
+	for _, k := range names {
+		v := sw[k]
+		vals := &listObject{}
+		if v != nil {
+			o, err := fromGoValue(v)
+			if err != nil {
+				return err
+			}
+			vals.Append(o)
+		}
+		ia.kwargs[k] = vals
+		ia.switches = append(ia.switches, switchArg{name: k, args: vals})
+	}
+	return nil
+}
+
 type Invokable struct {
 	inv  invokable
 	eval evaluator
@@ -245,6 +298,14 @@ type Invokable struct {
 	ec   *evalCtx
 }
 
+// String returns the string form of the invokable object, such as "(proc)".
+func (i Invokable) String() string {
+	if o, ok := i.inv.(object); ok {
+		return o.String()
+	}
+	return "(nil)"
+}
+
 func (i Invokable) IsNil() bool {
 	return i.inv == nil
 }
@@ -254,28 +315,35 @@ func (i Invokable) Invoke(ctx context.Context, args ...any) (any, error) {
 		return nil, nil
 	}
 
-	var err error
 	invArgs := invocationArgs{
 		eval: i.eval,
 		ec:   i.ec,
 		inst: i.inst,
 	}
 
-	invArgs.args, err = slices.MapWithError(args, func(a any) (object, error) {
-		return fromGoValue(a)
-	})
-	if err != nil {
-		return nil, err
+	for _, a := range args {
+		if sw, ok := a.(Switches); ok {
+			if err := invArgs.addSwitches(sw); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		o, err := fromGoValue(a)
+		if err != nil {
+			return nil, err
+		}
+		invArgs.args = append(invArgs.args, o)
 	}
 
 	res, err := i.inv.invoke(ctx, invArgs)
 	if err != nil {
-		return nil, err
+		var er errReturn
+		if !errors.As(err, &er) {
+			return nil, err
+		}
+		res = er.ret
 	}
 
-	goRes, ok := toGoValue(res)
-	if !ok {
-		return nil, errors.New("cannot convert result to Go Value")
-	}
-	return goRes, err
+	return invArgs.toGoValue(res)
 }
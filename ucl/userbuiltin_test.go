@@ -5,8 +5,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/netip"
 	"strings"
 	"testing"
+	"time"
 
 	"ucl.lmika.dev/ucl"
 
@@ -135,6 +137,32 @@ func TestInst_SetBuiltin(t *testing.T) {
 		assert.Equal(t, &pair{"Hello", "World"}, res)
 	})
 
+	t.Run("builtin return untyped list and hash as copies", func(t *testing.T) {
+		goList := []any{1, "two"}
+		goHash := map[string]any{"a": 1}
+		goSlice := []int{1, 2}
+
+		inst := ucl.New()
+		inst.SetBuiltin("goList", func(ctx context.Context, args ucl.CallArgs) (any, error) { return goList, nil })
+		inst.SetBuiltin("goHash", func(ctx context.Context, args ucl.CallArgs) (any, error) { return goHash, nil })
+		inst.SetBuiltin("goSlice", func(ctx context.Context, args ucl.CallArgs) (any, error) { return goSlice, nil })
+
+		res, err := inst.Eval(context.Background(), `set l (goList) ; set-at $l 0 9 ; push $l 3 ; $l`)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{9, "two", 3}, res)
+		assert.Equal(t, []any{1, "two"}, goList)
+
+		res, err = inst.Eval(context.Background(), `set h (goHash) ; set-key $h "a" 9 "b" 2 ; $h`)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"a": 9, "b": 2}, res)
+		assert.Equal(t, map[string]any{"a": 1}, goHash)
+
+		// Typed slices are still proxied, so changes are seen by the Go value
+		_, err = inst.Eval(context.Background(), `set-at (goSlice) 0 9`)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{9, 2}, goSlice)
+	})
+
 	t.Run("builtin operating on and returning proxy object", func(t *testing.T) {
 		type pair struct {
 			x, y string
@@ -206,6 +234,37 @@ func TestInst_SetBuiltin(t *testing.T) {
 		}
 	})
 
+	t.Run("maps returned by commands treated as hashes", func(t *testing.T) {
+		tests := []struct {
+			descr   string
+			expr    string
+			want    any
+			wantOut string
+		}{
+			{descr: "return as is", expr: `countMap`, want: map[string]int{"one": 1, "two": 2, "three": 3}},
+			{descr: "index", expr: `countMap | index two`, want: 2},
+			{descr: "dot", expr: `set m (countMap) ; $m.three`, want: 3},
+			{descr: "len", expr: `countMap | len`, want: 3},
+			{descr: "iterate over", expr: `foreach (countMap) { |k v| echo $k "=" $v }`, wantOut: "one=1\nthree=3\ntwo=2\n"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.descr, func(t *testing.T) {
+				outW := bytes.NewBuffer(nil)
+				inst := ucl.New(ucl.WithOut(outW))
+
+				inst.SetBuiltin("countMap", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+					return map[string]int{"one": 1, "two": 2, "three": 3}, nil
+				})
+
+				res, err := inst.Eval(context.Background(), tt.expr)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+				assert.Equal(t, tt.wantOut, outW.String())
+			})
+		}
+	})
+
 	t.Run("opaques returned as is", func(t *testing.T) {
 		type opaqueThingType struct {
 			x string
@@ -343,6 +402,98 @@ func TestCallArgs_Bind(t *testing.T) {
 	assert.Equal(t, "do string B: foo bar", vb)
 }
 
+func TestCallArgs_BindConversions(t *testing.T) {
+	type point struct {
+		X, Y int
+		Name string `ucl:"name"`
+	}
+
+	tests := []struct {
+		descr   string
+		eval    string
+		bind    func(args ucl.CallArgs) (any, error)
+		want    any
+		wantErr string
+	}{
+		{descr: "bool", eval: `test (eq 1 1)`, bind: bindAs[bool], want: true},
+		{descr: "int8", eval: `test -12`, bind: bindAs[int8], want: int8(-12)},
+		{descr: "int8 overflow", eval: `test 1234`, bind: bindAs[int8], wantErr: "arg 0: 1234 overflows int8"},
+		{descr: "int64", eval: `test 1234`, bind: bindAs[int64], want: int64(1234)},
+		{descr: "uint16", eval: `test 1234`, bind: bindAs[uint16], want: uint16(1234)},
+		{descr: "uint negative", eval: `test -1`, bind: bindAs[uint], wantErr: "arg 0: -1 overflows uint"},
+		{descr: "float64", eval: `test 12`, bind: bindAs[float64], want: float64(12)},
+		{descr: "slice of strings", eval: `test [a b c]`, bind: bindAs[[]string], want: []string{"a", "b", "c"}},
+		{descr: "slice of ints", eval: `test [1 2 3]`, bind: bindAs[[]int], want: []int{1, 2, 3}},
+		{descr: "slice bad element", eval: `test [1 "two" 3]`, bind: bindAs[[]int], wantErr: "arg 0: element 1: expected int but was string"},
+		{descr: "map of ints", eval: `test [a:1 b:2]`, bind: bindAs[map[string]int], want: map[string]int{"a": 1, "b": 2}},
+		{descr: "duration", eval: `test "1m30s"`, bind: bindAs[time.Duration], want: 90 * time.Second},
+		{descr: "duration bad type", eval: `test 123`, bind: bindAs[time.Duration], wantErr: "arg 0: expected time.Duration but was int"},
+		{descr: "text unmarshaler", eval: `test "127.0.0.1"`, bind: bindAs[netip.Addr], want: netip.MustParseAddr("127.0.0.1")},
+		{descr: "struct from hash", eval: `test [X:1 Y:2 name:"origin"]`, bind: bindAs[point], want: point{X: 1, Y: 2, Name: "origin"}},
+		{descr: "struct ptr from hash", eval: `test [X:3]`, bind: bindAs[*point], want: &point{X: 3}},
+		{descr: "struct bad field", eval: `test [X:"three"]`, bind: bindAs[point], wantErr: "arg 0: field 'X': expected int but was string"},
+		{descr: "int from string", eval: `test "123"`, bind: bindAs[int], wantErr: "arg 0: expected int but was string"},
+		{descr: "arg index", eval: `test 1 2 "three"`, bind: func(args ucl.CallArgs) (any, error) {
+			var x, y, z int
+			if err := args.Bind(&x); err != nil {
+				return nil, err
+			}
+			err := args.Bind(&y, &z)
+			return nil, err
+		}, wantErr: "arg 2: expected int but was string"},
+		{descr: "not enough args", eval: `test 1`, bind: func(args ucl.CallArgs) (any, error) {
+			var x, y int
+			err := args.Bind(&x, &y)
+			return nil, err
+		}, wantErr: "wrong number of arguments: expected at least 2 but was 1"},
+		{descr: "bool switch", eval: `test -flag`, bind: func(args ucl.CallArgs) (any, error) {
+			var flag bool
+			err := args.BindSwitch("flag", &flag)
+			return flag, err
+		}, want: true},
+		{descr: "bad switch", eval: `test -size "big"`, bind: func(args ucl.CallArgs) (any, error) {
+			var size int
+			err := args.BindSwitch("size", &size)
+			return size, err
+		}, wantErr: "switch -size: expected int but was string"},
+		{descr: "switch without arg", eval: `test -size`, bind: func(args ucl.CallArgs) (any, error) {
+			var size int
+			err := args.BindSwitch("size", &size)
+			return size, err
+		}, wantErr: "switch -size: expected 1 arg but was 0"},
+		{descr: "switch with too many args", eval: `test -flag 1 2`, bind: func(args ucl.CallArgs) (any, error) {
+			var flag bool
+			err := args.BindSwitch("flag", &flag)
+			return flag, err
+		}, wantErr: "switch -flag: expected 1 arg but was 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			inst := ucl.New()
+			inst.SetBuiltin("test", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+				return tt.bind(args)
+			})
+
+			res, err := inst.Eval(context.Background(), tt.eval)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+		})
+	}
+}
+
+func bindAs[T any](args ucl.CallArgs) (any, error) {
+	var v T
+	if err := args.Bind(&v); err != nil {
+		return nil, err
+	}
+	return ucl.Opaque(v), nil
+}
+
 func TestCallArgs_CanBind(t *testing.T) {
 	tests := []struct {
 		descr string
@@ -483,6 +634,108 @@ func TestCallArgs_MissingCommandHandler(t *testing.T) {
 	}
 }
 
+func TestCallArgs_SwitchNames(t *testing.T) {
+	tests := []struct {
+		descr string
+		eval  string
+		want  []any
+	}{
+		{descr: "no switches", eval: `sw "a"`, want: []any{}},
+		{descr: "in order given", eval: `sw -z -a 1 -m "x" "y"`, want: []any{"z", []any{}, "a", []any{1}, "m", []any{"x", "y"}}},
+		{descr: "long switches", eval: `sw "a" --all -n 5`, want: []any{"-all", []any{}, "n", []any{5}}},
+		{descr: "repeated switch", eval: `sw -e 1 -f -e 2`, want: []any{"e", []any{2}, "f", []any{}}},
+	}
+
+	newInst := func() *ucl.Inst {
+		inst := ucl.New()
+		inst.SetBuiltin("sw", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+			res := []any{}
+			for _, name := range args.SwitchNames() {
+				res = append(res, name, args.SwitchArgs(name))
+			}
+			return res, nil
+		})
+		return inst
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			res, err := newInst().Eval(context.Background(), tt.eval)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+
+	t.Run("switches from go are ordered by name", func(t *testing.T) {
+		res, err := newInst().Call(context.Background(), "sw", ucl.Switches{"z": 1, "a": nil})
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"a", []any{}, "z", []any{1}}, res)
+	})
+}
+
+func TestCallArgs_OrderedSwitches(t *testing.T) {
+	tests := []struct {
+		descr string
+		eval  string
+		want  []ucl.SwitchArg
+	}{
+		{descr: "no switches", eval: `sw "a"`, want: []ucl.SwitchArg{}},
+		{descr: "in order given", eval: `sw -z -a 1 "y"`, want: []ucl.SwitchArg{{Name: "z", Args: []any{}}, {Name: "a", Args: []any{1, "y"}}}},
+		{descr: "repeated switch", eval: `sw -e 1 -f -e 2 "x"`, want: []ucl.SwitchArg{
+			{Name: "e", Args: []any{1}},
+			{Name: "f", Args: []any{}},
+			{Name: "e", Args: []any{2, "x"}},
+		}},
+		{descr: "repeated flag", eval: `sw -v -v`, want: []ucl.SwitchArg{{Name: "v", Args: []any{}}, {Name: "v", Args: []any{}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			var res []ucl.SwitchArg
+
+			inst := ucl.New()
+			inst.SetBuiltin("sw", func(ctx context.Context, args ucl.CallArgs) (any, error) {
+				res = args.OrderedSwitches()
+				return nil, nil
+			})
+
+			_, err := inst.Eval(context.Background(), tt.eval)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestCallArgs_InstState(t *testing.T) {
+	type counterKey struct{}
+
+	module := ucl.Module{
+		Name: "counter",
+		Builtins: map[string]ucl.BuiltinHandler{
+			"next": func(ctx context.Context, args ucl.CallArgs) (any, error) {
+				n := args.InstState(counterKey{}, func() any { return new(int) }).(*int)
+				*n++
+				return *n, nil
+			},
+		},
+	}
+
+	inst1 := ucl.New(ucl.WithModule(module))
+	inst2 := ucl.New(ucl.WithModule(module))
+
+	res, err := inst1.Eval(context.Background(), `counter:next ; counter:next`)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res)
+
+	res, err = inst2.Eval(context.Background(), `counter:next`)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res)
+
+	res, err = inst1.Eval(context.Background(), `counter:next`)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res)
+}
+
 func TestCallArgs_IsTopLevel(t *testing.T) {
 	t.Run("true if the command is running at the top-level frame", func(t *testing.T) {
 		ctx := context.Background()
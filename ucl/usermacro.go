@@ -0,0 +1,85 @@
+package ucl
+
+import (
+	"context"
+)
+
+// MacroHandler is a builtin that receives its arguments unevaluated, allowing it to control
+// if and when they are evaluated. This can be used to implement control structures.
+type MacroHandler func(ctx context.Context, args MacroArgs) (any, error)
+
+type MacroArgs struct {
+	args macroArgs
+}
+
+func (ma *MacroArgs) NArgs() int {
+	return ma.args.nargs()
+}
+
+// HasPipe returns true if the macro is invoked as part of a pipeline.
+func (ma *MacroArgs) HasPipe() bool {
+	return ma.args.hasPipe
+}
+
+// PipeArg returns the value piped into the macro.
+func (ma *MacroArgs) PipeArg() (any, error) {
+	return ma.invocationArgs().toGoValue(ma.args.pipeArg)
+}
+
+func (ma *MacroArgs) Shift(n int) {
+	ma.args.shift(n)
+}
+
+// IdentIs returns true if argument n is the unquoted identifier ident.
+func (ma *MacroArgs) IdentIs(ctx context.Context, n int, ident string) bool {
+	return ma.args.identIs(ctx, n, ident)
+}
+
+// ShiftIdent returns and shifts off the next argument if it is an unquoted identifier.
+func (ma *MacroArgs) ShiftIdent(ctx context.Context) (string, bool) {
+	return ma.args.shiftIdent(ctx)
+}
+
+// EvalArg evaluates argument n.
+func (ma *MacroArgs) EvalArg(ctx context.Context, n int) (any, error) {
+	obj, err := ma.args.evalArg(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	return ma.invocationArgs().toGoValue(obj)
+}
+
+// EvalBlock evaluates argument n, which must be a block, within a new scope with the block
+// arguments set to args.
+func (ma *MacroArgs) EvalBlock(ctx context.Context, n int, args ...any) (any, error) {
+	blockArgs := make([]object, len(args))
+	for i, a := range args {
+		o, err := fromGoValue(a)
+		if err != nil {
+			return nil, err
+		}
+		blockArgs[i] = o
+	}
+
+	obj, err := ma.args.evalBlock(ctx, n, blockArgs, true)
+	if err != nil {
+		return nil, err
+	}
+	return ma.invocationArgs().toGoValue(obj)
+}
+
+func (ma *MacroArgs) invocationArgs() invocationArgs {
+	return invocationArgs{eval: ma.args.eval, inst: ma.args.eval.inst, ec: ma.args.ec}
+}
+
+type userMacro struct {
+	fn MacroHandler
+}
+
+func (u userMacro) invokeMacro(ctx context.Context, args macroArgs) (object, error) {
+	v, err := u.fn(ctx, MacroArgs{args: args})
+	if err != nil {
+		return nil, err
+	}
+	return fromGoValue(v)
+}